@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/mod/modfile"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const pinAnnotation = "depanalysis:pin"
+
+type Pin struct {
+	Reason string `json:"Reason,omitempty"`
+	Until  string `json:"Until,omitempty"`
+	Line   int    `json:"Line"`
+	until  time.Time
+}
+
+func (p *Pin) Active(now time.Time) bool {
+	if p == nil {
+		return false
+	}
+	return p.until.IsZero() || now.Before(p.until.AddDate(0, 0, 1))
+}
+
+func (p *Pin) String() string {
+	s := "pinned"
+	if p.Until != "" {
+		s += " until " + p.Until
+	}
+	if p.Reason != "" {
+		s += ": " + p.Reason
+	}
+	return s
+}
+
+func readPins(goModPath string) (map[string]*Pin, error) {
+	modFile, err := readRequirements(goModPath)
+	if err != nil {
+		return nil, err
+	}
+	return parsePins(modFile)
+}
+
+func parsePins(modFile *modfile.File) (map[string]*Pin, error) {
+	pins := make(map[string]*Pin)
+	var errs []string
+	for _, r := range modFile.Require {
+		comments := append(append([]modfile.Comment(nil), r.Syntax.Before...), r.Syntax.Suffix...)
+		for _, c := range comments {
+			text := strings.TrimSpace(strings.TrimPrefix(c.Token, "//"))
+			args, ok := strings.CutPrefix(text, pinAnnotation)
+			// Words that merely start with the annotation, such as
+			// depanalysis:pinned, are not pins.
+			if !ok || args != "" && !strings.ContainsAny(args[:1], " \t") {
+				continue
+			}
+			pin, err := parsePin(args)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("go.mod:%d: %s: %v", r.Syntax.Start.Line, r.Mod.Path, err))
+				continue
+			}
+			pin.Line = r.Syntax.Start.Line
+			pins[r.Mod.Path] = pin
+		}
+	}
+	if len(errs) > 0 {
+		return pins, fmt.Errorf("invalid %s annotations:\n%s", pinAnnotation, strings.Join(errs, "\n"))
+	}
+	return pins, nil
+}
+
+func parsePin(args string) (*Pin, error) {
+	pin := &Pin{}
+	rest := strings.TrimSpace(args)
+	for rest != "" {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("expected key=value, got %q", rest)
+		}
+		if strings.HasPrefix(value, `"`) {
+			quoted, err := strconv.QuotedPrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("bad quoted value for %s", key)
+			}
+			rest = value[len(quoted):]
+			if rest != "" && !strings.ContainsAny(rest[:1], " \t") {
+				return nil, fmt.Errorf("expected space after quoted value for %s, got %q", key, rest)
+			}
+			value, _ = strconv.Unquote(quoted)
+		} else if i := strings.IndexAny(value, " \t"); i >= 0 {
+			value, rest = value[:i], value[i:]
+		} else {
+			rest = ""
+		}
+		rest = strings.TrimSpace(rest)
+
+		switch key {
+		case "reason":
+			pin.Reason = value
+		case "until":
+			t, err := time.Parse(time.DateOnly, value)
+			if err != nil {
+				return nil, fmt.Errorf("until must be YYYY-MM-DD, got %q", value)
+			}
+			pin.Until, pin.until = value, t
+		default:
+			return nil, fmt.Errorf("unknown key %q", key)
+		}
+	}
+	return pin, nil
+}
+
+func applyPins(deps []ModuleInfo, pins map[string]*Pin) {
+	for i := range deps {
+		deps[i].Pin = pins[deps[i].Path]
+	}
+}
+
+func upgradable(dep ModuleInfo) bool {
+	return dep.Update != nil && !dep.Pin.Active(time.Now())
+}
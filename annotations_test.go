@@ -0,0 +1,85 @@
+package main
+
+import (
+	"golang.org/x/mod/modfile"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePin(t *testing.T) {
+	tests := []struct {
+		args    string
+		want    Pin
+		wantErr bool
+	}{
+		{args: "", want: Pin{}},
+		{args: " reason=flaky", want: Pin{Reason: "flaky"}},
+		{args: ` reason="ABI break" until=2026-12-01`, want: Pin{Reason: "ABI break", Until: "2026-12-01"}},
+		{args: "\tuntil=2026-12-01\treason=\"needs \\\"v2\\\" API\"", want: Pin{Reason: `needs "v2" API`, Until: "2026-12-01"}},
+		{args: ` reason=""`, want: Pin{}},
+		{args: ` reason="ABI break"until=2026-12-01`, wantErr: true},
+		{args: ` reason="unterminated`, wantErr: true},
+		{args: " until=2026-13-01", wantErr: true},
+		{args: " until=12/01/2026", wantErr: true},
+		{args: " until=", wantErr: true},
+		{args: " owner=me", wantErr: true},
+		{args: " reason", wantErr: true},
+		{args: " some reason=x", wantErr: true},
+	}
+	for _, tt := range tests {
+		pin, err := parsePin(tt.args)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parsePin(%q) = %+v, want error", tt.args, pin)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parsePin(%q): %v", tt.args, err)
+			continue
+		}
+		if pin.Reason != tt.want.Reason || pin.Until != tt.want.Until {
+			t.Errorf("parsePin(%q) = %+v, want %+v", tt.args, pin, tt.want)
+		}
+	}
+}
+
+func TestParsePins(t *testing.T) {
+	data := `module example.com/m
+
+require (
+	// depanalysis:pin reason="waiting on upstream"
+	example.com/a v1.0.0
+	example.com/b v1.0.0 // depanalysis:pin until=2026-01-31
+	example.com/c v1.0.0 // depanalysis:pin bogus=1
+	example.com/d v1.0.0 // unrelated comment
+	example.com/e v1.0.0 // depanalysis:pinned-by-renovate
+	example.com/f v1.0.0 // depanalysis:pin
+)
+`
+	f, err := modfile.Parse("go.mod", []byte(data), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pins, err := parsePins(f)
+	if err == nil {
+		t.Error("invalid annotation on example.com/c not reported")
+	} else if !strings.Contains(err.Error(), "example.com/c") || strings.Contains(err.Error(), "example.com/e") {
+		t.Errorf("errors = %v, want only example.com/c reported", err)
+	}
+	if len(pins) != 3 || pins["example.com/f"] == nil || pins["example.com/a"].Reason != "waiting on upstream" || pins["example.com/a"].Line != 5 {
+		t.Fatalf("pins = %+v", pins)
+	}
+
+	b := pins["example.com/b"]
+	if !b.Active(time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC)) {
+		t.Error("pin inactive on its until date")
+	}
+	if b.Active(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("pin active after its until date")
+	}
+	if !pins["example.com/a"].Active(time.Now()) {
+		t.Error("pin without until date inactive")
+	}
+}
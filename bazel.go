@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type bazelRepository struct {
+	Name       string
+	ImportPath string
+	Version    string
+	Sum        string
+	File       string
+	Line       int
+}
+
+type BazelDrift struct {
+	ImportPath string
+	Kind       string
+	GoMod      string
+	Bazel      string
+	File       string
+	Line       int
+}
+
+var (
+	goRepositoryRule = regexp.MustCompile(`(?s)go_repository\((.*?)\n\s*\)`)
+	bazelAttr        = regexp.MustCompile(`(\w+)\s*=\s*"([^"]*)"`)
+	bazelNameChars   = regexp.MustCompile(`[^A-Za-z0-9_]`)
+)
+
+func bazelRepoName(importPath string) string {
+	parts := strings.Split(importPath, "/")
+	host := strings.Split(parts[0], ".")
+	for i, j := 0, len(host)-1; i < j; i, j = i+1, j-1 {
+		host[i], host[j] = host[j], host[i]
+	}
+	name := strings.Join(append(host, parts[1:]...), "_")
+	return strings.ToLower(bazelNameChars.ReplaceAllString(name, "_"))
+}
+
+func bazelRepositories(modules []ModuleInfo) []bazelRepository {
+	var repos []bazelRepository
+	for _, m := range modules {
+		if m.Main || m.Version == "" {
+			continue
+		}
+		repos = append(repos, bazelRepository{
+			Name:       bazelRepoName(m.Path),
+			ImportPath: m.Path,
+			Version:    m.Version,
+			Sum:        m.Sum,
+		})
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+	return repos
+}
+
+func renderBazelDeps(modules []ModuleInfo) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("load(\"@bazel_gazelle//:deps.bzl\", \"go_repository\")\n\n")
+	buf.WriteString("def go_dependencies():\n")
+	repos := bazelRepositories(modules)
+	if len(repos) == 0 {
+		buf.WriteString("    pass\n")
+	}
+	for _, r := range repos {
+		fmt.Fprintf(&buf, "    go_repository(\n")
+		fmt.Fprintf(&buf, "        name = %q,\n", r.Name)
+		fmt.Fprintf(&buf, "        importpath = %q,\n", r.ImportPath)
+		if r.Sum != "" {
+			fmt.Fprintf(&buf, "        sum = %q,\n", r.Sum)
+		}
+		fmt.Fprintf(&buf, "        version = %q,\n", r.Version)
+		fmt.Fprintf(&buf, "    )\n")
+	}
+	return buf.Bytes()
+}
+
+func renderBazelModule(modules []ModuleInfo) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("go_deps = use_extension(\"@gazelle//:extensions.bzl\", \"go_deps\")\n")
+	buf.WriteString("go_deps.from_file(go_mod = \"//:go.mod\")\n")
+	var direct []string
+	for _, m := range modules {
+		if !m.Main && !m.Indirect {
+			direct = append(direct, bazelRepoName(m.Path))
+		}
+	}
+	sort.Strings(direct)
+	if len(direct) == 0 {
+		return buf.Bytes()
+	}
+	buf.WriteString("use_repo(\n    go_deps,\n")
+	for _, name := range direct {
+		fmt.Fprintf(&buf, "    %q,\n", name)
+	}
+	buf.WriteString(")\n")
+	return buf.Bytes()
+}
+
+func parseBazelDeps(file string) ([]bazelRepository, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var repos []bazelRepository
+	for _, loc := range goRepositoryRule.FindAllSubmatchIndex(data, -1) {
+		r := bazelRepository{File: file, Line: bytes.Count(data[:loc[0]], []byte("\n")) + 1}
+		for _, attr := range bazelAttr.FindAllSubmatch(data[loc[2]:loc[3]], -1) {
+			value := string(attr[2])
+			switch string(attr[1]) {
+			case "name":
+				r.Name = value
+			case "importpath":
+				r.ImportPath = value
+			case "version":
+				r.Version = value
+			case "sum":
+				r.Sum = value
+			}
+		}
+		if r.ImportPath != "" {
+			repos = append(repos, r)
+		}
+	}
+	return repos, nil
+}
+
+func findBazelDeps(root string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && (info.Name() == ".git" || info.Name() == "vendor") {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && info.Name() == "deps.bzl" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// bazelDrift compares the deps.bzl files under root with the build list.
+// It also returns the files it compared; with none there is no drift to
+// report.
+func bazelDrift(root string, modules []ModuleInfo) ([]BazelDrift, []string, error) {
+	files, err := findBazelDeps(root)
+	if err != nil || len(files) == 0 {
+		return nil, nil, err
+	}
+	declared := make(map[string]bazelRepository)
+	for _, f := range files {
+		repos, err := parseBazelDeps(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, r := range repos {
+			declared[r.ImportPath] = r
+		}
+	}
+
+	var drift []BazelDrift
+	for _, want := range bazelRepositories(modules) {
+		have, ok := declared[want.ImportPath]
+		delete(declared, want.ImportPath)
+		switch {
+		case !ok:
+			drift = append(drift, BazelDrift{ImportPath: want.ImportPath, Kind: "missing", GoMod: want.Version})
+		case have.Version != want.Version:
+			drift = append(drift, BazelDrift{ImportPath: want.ImportPath, Kind: "version", GoMod: want.Version, Bazel: have.Version, File: have.File, Line: have.Line})
+		case have.Sum != "" && want.Sum != "" && have.Sum != want.Sum:
+			drift = append(drift, BazelDrift{ImportPath: want.ImportPath, Kind: "sum", GoMod: want.Sum, Bazel: have.Sum, File: have.File, Line: have.Line})
+		}
+	}
+	for _, have := range declared {
+		drift = append(drift, BazelDrift{ImportPath: have.ImportPath, Kind: "extra", Bazel: have.Version, File: have.File, Line: have.Line})
+	}
+	for i := range drift {
+		if drift[i].File != "" {
+			if rel, err := filepath.Rel(root, drift[i].File); err == nil {
+				drift[i].File = rel
+			}
+		}
+	}
+	sort.Slice(drift, func(i, j int) bool { return drift[i].ImportPath < drift[j].ImportPath })
+	return drift, files, nil
+}
+
+func printBazelDrift(drift []BazelDrift, files []string) {
+	if len(files) == 0 {
+		fmt.Println("No deps.bzl files found; Bazel drift not checked.")
+		return
+	}
+	if len(drift) == 0 {
+		fmt.Println("Bazel go_repository rules match go.mod.")
+		return
+	}
+	fmt.Println("Bazel go_repository drift:")
+	for _, d := range drift {
+		switch d.Kind {
+		case "missing":
+			fmt.Printf("- %s: missing from deps.bzl (go.mod has %s)\n", d.ImportPath, d.GoMod)
+		case "extra":
+			fmt.Printf("- %s: declared at %s:%d but not in the build list\n", d.ImportPath, d.File, d.Line)
+		default:
+			fmt.Printf("- %s: %s mismatch at %s:%d: go.mod %s, deps.bzl %s\n", d.ImportPath, d.Kind, d.File, d.Line, d.GoMod, d.Bazel)
+		}
+	}
+}
+
+func runBazel(root, modDir string, opts options) error {
+	modules, err := listModules(modDir, false)
+	if err != nil {
+		return err
+	}
+	if opts.bazelDeps != "" {
+		if err := os.WriteFile(opts.bazelDeps, renderBazelDeps(modules), 0o644); err != nil {
+			return err
+		}
+	}
+	if opts.bazelModule != "" {
+		if err := os.WriteFile(opts.bazelModule, renderBazelModule(modules), 0o644); err != nil {
+			return err
+		}
+	}
+	if opts.bazelDrift && opts.format == "text" {
+		drift, files, err := bazelDrift(root, modules)
+		if err != nil {
+			return err
+		}
+		printBazelDrift(drift, files)
+	}
+	return nil
+}
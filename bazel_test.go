@@ -0,0 +1,156 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBazelRepoName(t *testing.T) {
+	tests := []struct{ path, want string }{
+		{"github.com/stretchr/testify", "com_github_stretchr_testify"},
+		{"golang.org/x/mod", "org_golang_x_mod"},
+		{"gopkg.in/yaml.v3", "in_gopkg_yaml_v3"},
+		{"github.com/BurntSushi/toml", "com_github_burntsushi_toml"},
+		{"github.com/foo/bar-baz/v2", "com_github_foo_bar_baz_v2"},
+		{"go.uber.org/zap", "org_uber_go_zap"},
+		{"example.com/a~b", "com_example_a_b"},
+	}
+	for _, tt := range tests {
+		if got := bazelRepoName(tt.path); got != tt.want {
+			t.Errorf("bazelRepoName(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseBazelDeps(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"deps.bzl": `load("@bazel_gazelle//:deps.bzl", "go_repository")
+
+def go_dependencies():
+    go_repository(
+        name = "com_github_a_b",
+        importpath = "github.com/a/b",
+        sum = "h1:ab=",
+        version = "v1.0.0",
+    )
+
+    # Pinned by hand.
+    go_repository(
+        name = "org_golang_x_mod",
+        build_file_proto_mode = "disable",
+        importpath = "golang.org/x/mod",
+        version = "v0.26.0",
+    )
+    go_repository(
+        name = "no_importpath",
+        version = "v1.0.0",
+    )
+`,
+	})
+	file := filepath.Join(dir, "deps.bzl")
+	got, err := parseBazelDeps(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []bazelRepository{
+		{Name: "com_github_a_b", ImportPath: "github.com/a/b", Version: "v1.0.0", Sum: "h1:ab=", File: file, Line: 4},
+		{Name: "org_golang_x_mod", ImportPath: "golang.org/x/mod", Version: "v0.26.0", File: file, Line: 12},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseBazelDeps =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if _, err := parseBazelDeps(filepath.Join(dir, "missing.bzl")); err == nil {
+		t.Error("parsing a missing file succeeded")
+	}
+}
+
+func TestRenderBazelDepsRoundTrip(t *testing.T) {
+	modules := []ModuleInfo{
+		{Path: "example.com/m", Main: true},
+		{Path: "golang.org/x/mod", Version: "v0.26.0", Sum: "h1:mod="},
+		{Path: "github.com/a/b", Version: "v1.0.0"},
+		{Path: "example.com/local"},
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"deps.bzl": string(renderBazelDeps(modules))})
+	repos, err := parseBazelDeps(filepath.Join(dir, "deps.bzl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range repos {
+		repos[i].File, repos[i].Line = "", 0
+	}
+	if want := bazelRepositories(modules); !reflect.DeepEqual(repos, want) {
+		t.Errorf("rendered deps.bzl parses as\n%+v\nwant\n%+v", repos, want)
+	}
+}
+
+func TestBazelDrift(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"deps.bzl": `def go_dependencies():
+    go_repository(
+        name = "com_github_a_version",
+        importpath = "github.com/a/version",
+        version = "v1.0.0",
+    )
+    go_repository(
+        name = "com_github_a_sum",
+        importpath = "github.com/a/sum",
+        sum = "h1:old=",
+        version = "v1.0.0",
+    )
+    go_repository(
+        name = "com_github_a_same",
+        importpath = "github.com/a/same",
+        sum = "h1:same=",
+        version = "v1.0.0",
+    )
+`,
+		"third_party/deps.bzl": `def more():
+    go_repository(
+        name = "com_github_a_extra",
+        importpath = "github.com/a/extra",
+        version = "v0.1.0",
+    )
+    go_repository(
+        name = "com_github_a_nosum",
+        importpath = "github.com/a/nosum",
+        version = "v1.0.0",
+    )
+`,
+		"vendor/deps.bzl": "go_repository(\n    importpath = \"github.com/a/vendored\",\n    version = \"v1.0.0\",\n)\n",
+	})
+	modules := []ModuleInfo{
+		{Path: "example.com/m", Main: true},
+		{Path: "github.com/a/version", Version: "v1.2.0"},
+		{Path: "github.com/a/sum", Version: "v1.0.0", Sum: "h1:new="},
+		{Path: "github.com/a/same", Version: "v1.0.0", Sum: "h1:same="},
+		{Path: "github.com/a/nosum", Version: "v1.0.0", Sum: "h1:nosum="},
+		{Path: "github.com/a/missing", Version: "v2.0.0"},
+	}
+	drift, files, err := bazelDrift(root, modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("compared files %q, want deps.bzl and third_party/deps.bzl", files)
+	}
+	want := []BazelDrift{
+		{ImportPath: "github.com/a/extra", Kind: "extra", Bazel: "v0.1.0", File: filepath.Join("third_party", "deps.bzl"), Line: 2},
+		{ImportPath: "github.com/a/missing", Kind: "missing", GoMod: "v2.0.0"},
+		{ImportPath: "github.com/a/sum", Kind: "sum", GoMod: "h1:new=", Bazel: "h1:old=", File: "deps.bzl", Line: 7},
+		{ImportPath: "github.com/a/version", Kind: "version", GoMod: "v1.2.0", Bazel: "v1.0.0", File: "deps.bzl", Line: 2},
+	}
+	if !reflect.DeepEqual(drift, want) {
+		t.Errorf("drift =\n%+v\nwant\n%+v", drift, want)
+	}
+
+	drift, files, err = bazelDrift(t.TempDir(), modules)
+	if err != nil || drift != nil || files != nil {
+		t.Errorf("drift without deps.bzl = %+v, %q, %v; want nothing", drift, files, err)
+	}
+}
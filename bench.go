@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type benchKey struct {
+	Package string
+	Name    string
+	Unit    string
+}
+
+type BenchComparison struct {
+	benchKey
+	OldMean   float64
+	NewMean   float64
+	Delta     float64
+	PValue    float64
+	Samples   [2]int
+	Regressed bool
+}
+
+type BenchReport struct {
+	Module      ModuleInfo
+	Comparisons []BenchComparison
+	Err         error
+}
+
+func analyzeBenchmarks(modDir string, deps []ModuleInfo, pkgs []string, bench string, count int, alpha float64) ([]BenchReport, error) {
+	work, err := os.MkdirTemp("", "go-dep-bench")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(work)
+
+	baseDir, err := newWorkCopy(modDir, work, "base")
+	if err != nil {
+		return nil, err
+	}
+	base, err := runBenchmarks(baseDir, pkgs, bench, count)
+	if err != nil {
+		return nil, fmt.Errorf("error running benchmarks on current versions: %v", err)
+	}
+	os.RemoveAll(baseDir)
+
+	var reports []BenchReport
+	for i, dep := range deps {
+		if !upgradable(dep) {
+			continue
+		}
+		report := BenchReport{Module: dep}
+		dir, err := newWorkCopy(modDir, work, fmt.Sprintf("update-%d", i))
+		if err != nil {
+			return nil, err
+		}
+		if err := applyUpdate(dir, dep); err != nil {
+			report.Err = err
+		} else if updated, err := runBenchmarks(dir, pkgs, bench, count); err != nil {
+			report.Err = err
+		} else {
+			report.Comparisons = compareBenchmarks(base, updated, alpha)
+		}
+		os.RemoveAll(dir)
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func runBenchmarks(dir string, pkgs []string, bench string, count int) (map[benchKey][]float64, error) {
+	args := []string{"test", "-mod=mod", "-run", "^$", "-bench", bench, "-count", strconv.Itoa(count)}
+	out, err := runGo(dir, append(args, pkgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseBenchOutput(out), nil
+}
+
+func parseBenchOutput(out []byte) map[benchKey][]float64 {
+	results := make(map[benchKey][]float64)
+	var pkg string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "pkg: ") {
+			pkg = strings.TrimPrefix(line, "pkg: ")
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+		name := fields[0]
+		if i := strings.LastIndex(name, "-"); i > 0 {
+			if _, err := strconv.Atoi(name[i+1:]); err == nil {
+				name = name[:i]
+			}
+		}
+		for i := 2; i+1 < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				break
+			}
+			key := benchKey{Package: pkg, Name: name, Unit: fields[i+1]}
+			results[key] = append(results[key], v)
+		}
+	}
+	return results
+}
+
+func compareBenchmarks(old, updated map[benchKey][]float64, alpha float64) []BenchComparison {
+	var comparisons []BenchComparison
+	for key, a := range old {
+		b, ok := updated[key]
+		if !ok {
+			continue
+		}
+		c := BenchComparison{
+			benchKey: key,
+			OldMean:  mean(a),
+			NewMean:  mean(b),
+			PValue:   mannWhitneyU(a, b),
+			Samples:  [2]int{len(a), len(b)},
+		}
+		if c.OldMean != 0 {
+			c.Delta = (c.NewMean - c.OldMean) / c.OldMean * 100
+		}
+		worse := c.Delta > 0
+		if higherIsBetter(key.Unit) {
+			worse = c.Delta < 0
+		}
+		c.Regressed = worse && c.PValue < alpha
+		comparisons = append(comparisons, c)
+	}
+	sort.Slice(comparisons, func(i, j int) bool {
+		ci, cj := comparisons[i], comparisons[j]
+		if ci.Package != cj.Package {
+			return ci.Package < cj.Package
+		}
+		if ci.Name != cj.Name {
+			return ci.Name < cj.Name
+		}
+		return ci.Unit < cj.Unit
+	})
+	return comparisons
+}
+
+func higherIsBetter(unit string) bool {
+	return strings.HasSuffix(unit, "/s")
+}
+
+func mean(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+// mannWhitneyU returns the two-sided p-value of the Mann-Whitney U test,
+// the same test benchstat uses, via the normal approximation with tie
+// correction.
+func mannWhitneyU(a, b []float64) float64 {
+	n1, n2 := float64(len(a)), float64(len(b))
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+	type sample struct {
+		v     float64
+		first bool
+	}
+	all := make([]sample, 0, len(a)+len(b))
+	for _, v := range a {
+		all = append(all, sample{v, true})
+	}
+	for _, v := range b {
+		all = append(all, sample{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+
+	var r1, tieSum float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		t := float64(j - i)
+		tieSum += t*t*t - t
+		for k := i; k < j; k++ {
+			if all[k].first {
+				r1 += rank
+			}
+		}
+		i = j
+	}
+
+	u := r1 - n1*(n1+1)/2
+	n := n1 + n2
+	mu := n1 * n2 / 2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - tieSum/(n*(n-1))))
+	if sigma == 0 {
+		return 1
+	}
+	z := (math.Abs(u-mu) - 0.5) / sigma
+	if z < 0 {
+		z = 0
+	}
+	return math.Erfc(z / math.Sqrt2)
+}
+
+func printBenchmarks(reports []BenchReport) {
+	if len(reports) == 0 {
+		fmt.Println("No benchmark results to report.")
+		return
+	}
+	fmt.Println("Benchmark comparison:")
+	for _, r := range reports {
+		fmt.Printf("- %s: %s -> %s\n", r.Module.Path, r.Module.Version, r.Module.Update.Version)
+		if r.Err != nil {
+			fmt.Printf("    benchmarks failed: %v\n", r.Err)
+			continue
+		}
+		for _, c := range r.Comparisons {
+			marker := ""
+			if c.Regressed {
+				marker = "  REGRESSION"
+			}
+			fmt.Printf("    %s %s: %.4g -> %.4g %s (%+.2f%%, p=%.3f n=%d+%d)%s\n",
+				c.Package, c.Name, c.OldMean, c.NewMean, c.Unit, c.Delta, c.PValue, c.Samples[0], c.Samples[1], marker)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMannWhitneyU(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{"separated", []float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 0.012185},
+		{"separated reversed", []float64{6, 7, 8, 9, 10}, []float64{1, 2, 3, 4, 5}, 0.012185},
+		{"interleaved", []float64{1, 3, 5, 7, 9}, []float64{2, 4, 6, 8, 10}, 0.676103},
+		{"with ties", []float64{1, 2, 2, 3}, []float64{2, 3, 3, 4}, 0.172034},
+		{"identical", []float64{5, 5, 5}, []float64{5, 5, 5}, 1},
+		{"empty", nil, []float64{1, 2}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mannWhitneyU(tt.a, tt.b); math.Abs(got-tt.want) > 1e-5 {
+				t.Errorf("mannWhitneyU(%v, %v) = %.6f, want %.6f", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBenchOutput(t *testing.T) {
+	out := []byte(`goos: linux
+pkg: example.com/m/a
+BenchmarkFoo-8   	 1000	      1200 ns/op	  64 B/op	   2 allocs/op
+BenchmarkFoo-8   	 1000	      1300 ns/op	  64 B/op	   2 allocs/op
+BenchmarkBar/sub-case-8 	 500	      10.5 MB/s
+PASS
+pkg: example.com/m/b
+BenchmarkFoo 	 10	      99 ns/op
+`)
+	got := parseBenchOutput(out)
+	checks := []struct {
+		key  benchKey
+		want []float64
+	}{
+		{benchKey{"example.com/m/a", "BenchmarkFoo", "ns/op"}, []float64{1200, 1300}},
+		{benchKey{"example.com/m/a", "BenchmarkFoo", "allocs/op"}, []float64{2, 2}},
+		{benchKey{"example.com/m/a", "BenchmarkBar/sub-case", "MB/s"}, []float64{10.5}},
+		{benchKey{"example.com/m/b", "BenchmarkFoo", "ns/op"}, []float64{99}},
+	}
+	for _, c := range checks {
+		if !equalFloats(got[c.key], c.want) {
+			t.Errorf("%v = %v, want %v", c.key, got[c.key], c.want)
+		}
+	}
+	if len(got) != 5 {
+		t.Errorf("got %d series, want 5: %v", len(got), got)
+	}
+}
+
+func TestCompareBenchmarksRegression(t *testing.T) {
+	key := benchKey{"p", "BenchmarkX", "ns/op"}
+	throughput := benchKey{"p", "BenchmarkX", "MB/s"}
+	old := map[benchKey][]float64{
+		key:        {100, 101, 99, 100, 102, 98, 100, 101},
+		throughput: {50, 51, 49, 50, 52, 48, 50, 51},
+	}
+	updated := map[benchKey][]float64{
+		key:        {120, 121, 119, 120, 122, 118, 120, 121},
+		throughput: {60, 61, 59, 60, 62, 58, 60, 61},
+	}
+	for _, c := range compareBenchmarks(old, updated, 0.05) {
+		switch c.benchKey {
+		case key:
+			if !c.Regressed {
+				t.Errorf("slower ns/op not reported as regression: %+v", c)
+			}
+		case throughput:
+			if c.Regressed {
+				t.Errorf("higher MB/s reported as regression: %+v", c)
+			}
+		}
+	}
+}
+
+func equalFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
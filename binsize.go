@@ -0,0 +1,304 @@
+package main
+
+import (
+	"debug/elf"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const maxSymbolDeltas = 5
+
+type BinarySize struct {
+	Package     string
+	Before      int64
+	After       int64
+	SymbolDelta []PackageSizeDelta
+	// SymbolErr is set when the per-package breakdown could not be
+	// computed for either binary.
+	SymbolErr error
+}
+
+type PackageSizeDelta struct {
+	Package string
+	Before  int64
+	After   int64
+}
+
+type BinarySizeReport struct {
+	Module   ModuleInfo
+	Binaries []BinarySize
+	Err      error
+}
+
+type builtBinary struct {
+	size       int64
+	symbols    map[string]int64
+	symbolsErr error
+}
+
+func analyzeBinarySizes(modDir string, deps []ModuleInfo) ([]BinarySizeReport, error) {
+	mains, err := listMainPackages(modDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(mains) == 0 {
+		return nil, nil
+	}
+
+	work, err := os.MkdirTemp("", "go-dep-binsize")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(work)
+
+	baseDir, err := newWorkCopy(modDir, work, "base")
+	if err != nil {
+		return nil, err
+	}
+	base, err := buildMainPackages(baseDir, mains)
+	if err != nil {
+		return nil, fmt.Errorf("error building current versions: %v", err)
+	}
+	os.RemoveAll(baseDir)
+
+	var reports []BinarySizeReport
+	for i, dep := range deps {
+		if !upgradable(dep) {
+			continue
+		}
+		report := BinarySizeReport{Module: dep}
+		dir, err := newWorkCopy(modDir, work, fmt.Sprintf("update-%d", i))
+		if err != nil {
+			return nil, err
+		}
+		after, err := buildUpdated(dir, dep, mains)
+		os.RemoveAll(dir)
+		if err != nil {
+			report.Err = err
+			reports = append(reports, report)
+			continue
+		}
+		for _, pkg := range mains {
+			b, a := base[pkg], after[pkg]
+			bin := BinarySize{Package: pkg, Before: b.size, After: a.size}
+			switch {
+			case b.symbolsErr != nil:
+				bin.SymbolErr = b.symbolsErr
+			case a.symbolsErr != nil:
+				bin.SymbolErr = a.symbolsErr
+			default:
+				bin.SymbolDelta = diffSymbolSizes(b.symbols, a.symbols)
+			}
+			report.Binaries = append(report.Binaries, bin)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func buildUpdated(dir string, dep ModuleInfo, mains []string) (map[string]builtBinary, error) {
+	if err := applyUpdate(dir, dep); err != nil {
+		return nil, err
+	}
+	return buildMainPackages(dir, mains)
+}
+
+func buildMainPackages(dir string, mains []string) (map[string]builtBinary, error) {
+	outDir := filepath.Join(dir, ".binsize-out")
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return nil, err
+	}
+	pkgs := listDependencyPackages(dir, mains)
+	built := make(map[string]builtBinary)
+	for i, pkg := range mains {
+		out := filepath.Join(outDir, fmt.Sprintf("%d-%s", i, filepath.Base(pkg)))
+		if _, err := runGo(dir, "build", "-mod=mod", "-o", out, pkg); err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(out)
+		if err != nil {
+			return nil, err
+		}
+		symbols, err := symbolSizesByPackage(out, pkgs)
+		if err != nil {
+			err = fmt.Errorf("reading symbols of %s: %v", pkg, err)
+		}
+		built[pkg] = builtBinary{size: info.Size(), symbols: symbols, symbolsErr: err}
+	}
+	return built, nil
+}
+
+// listDependencyPackages returns the import paths linked into mains, used to
+// attribute symbols to packages whose paths contain dots. It returns nil if
+// the packages cannot be listed, and symbols fall back to a heuristic.
+func listDependencyPackages(dir string, mains []string) map[string]bool {
+	out, err := runGo(dir, append([]string{"list", "-mod=mod", "-deps", "-f", "{{.ImportPath}}"}, mains...)...)
+	if err != nil {
+		return nil
+	}
+	pkgs := make(map[string]bool)
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			pkgs[line] = true
+		}
+	}
+	return pkgs
+}
+
+func symbolSizesByPackage(path string, pkgs map[string]bool) (map[string]int64, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	syms, err := f.Symbols()
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]int64)
+	for _, sym := range syms {
+		if sym.Size == 0 || sym.Section == elf.SHN_UNDEF {
+			continue
+		}
+		sizes[symbolPackage(sym.Name, pkgs)] += int64(sym.Size)
+	}
+	return sizes, nil
+}
+
+// symbolPackage returns the package a symbol belongs to. Type arguments of
+// generic instantiations are dropped first, since they name other packages.
+// The longest known package path followed by a dot wins; without one, the
+// package ends at the first dot after the last slash, which misreads paths
+// such as gopkg.in/yaml.v3.
+func symbolPackage(name string, pkgs map[string]bool) string {
+	for _, prefix := range []string{"type:", "go:"} {
+		if strings.HasPrefix(name, prefix) {
+			return "<" + strings.TrimSuffix(prefix, ":") + ">"
+		}
+	}
+	name = stripTypeArgs(name)
+	best := ""
+	for i := 0; i < len(name); i++ {
+		if name[i] == '.' && pkgs[name[:i]] {
+			best = name[:i]
+		}
+	}
+	if best != "" {
+		return best
+	}
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return name
+	}
+	return name[:slash+1+dot]
+}
+
+// stripTypeArgs removes the bracketed, possibly nested, type arguments from
+// a symbol name.
+func stripTypeArgs(name string) string {
+	if !strings.Contains(name, "[") {
+		return name
+	}
+	var b strings.Builder
+	depth := 0
+	for _, r := range name {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func diffSymbolSizes(before, after map[string]int64) []PackageSizeDelta {
+	if before == nil || after == nil {
+		return nil
+	}
+	var deltas []PackageSizeDelta
+	for pkg, b := range before {
+		if a := after[pkg]; a != b {
+			deltas = append(deltas, PackageSizeDelta{Package: pkg, Before: b, After: a})
+		}
+	}
+	for pkg, a := range after {
+		if _, ok := before[pkg]; !ok {
+			deltas = append(deltas, PackageSizeDelta{Package: pkg, After: a})
+		}
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		di, dj := abs64(deltas[i].After-deltas[i].Before), abs64(deltas[j].After-deltas[j].Before)
+		if di != dj {
+			return di > dj
+		}
+		return deltas[i].Package < deltas[j].Package
+	})
+	return deltas
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func growthPercent(before, after int64) float64 {
+	if before == 0 {
+		return 0
+	}
+	return float64(after-before) / float64(before) * 100
+}
+
+func printBinarySizes(reports []BinarySizeReport) {
+	if len(reports) == 0 {
+		fmt.Println("No binary size impact to report.")
+		return
+	}
+	fmt.Println("Binary size impact:")
+	for _, r := range reports {
+		fmt.Printf("- %s: %s -> %s\n", r.Module.Path, r.Module.Version, r.Module.Update.Version)
+		if r.Err != nil {
+			fmt.Printf("    build failed: %v\n", r.Err)
+			continue
+		}
+		for _, bin := range r.Binaries {
+			fmt.Printf("    %s: %d -> %d bytes (%+d, %+.2f%%)\n",
+				bin.Package, bin.Before, bin.After, bin.After-bin.Before, growthPercent(bin.Before, bin.After))
+			if bin.SymbolErr != nil {
+				fmt.Printf("        no per-package breakdown: %v\n", bin.SymbolErr)
+				continue
+			}
+			for i, d := range bin.SymbolDelta {
+				if i == maxSymbolDeltas {
+					fmt.Printf("        ... %d more packages changed\n", len(bin.SymbolDelta)-maxSymbolDeltas)
+					break
+				}
+				fmt.Printf("        %s: %+d\n", d.Package, d.After-d.Before)
+			}
+		}
+	}
+}
+
+func binarySizeViolations(reports []BinarySizeReport, threshold float64) []string {
+	if threshold <= 0 {
+		return nil
+	}
+	var violations []string
+	for _, r := range reports {
+		for _, bin := range r.Binaries {
+			if pct := growthPercent(bin.Before, bin.After); pct > threshold {
+				violations = append(violations, fmt.Sprintf("%s@%s: %s grew by %.2f%%",
+					r.Module.Path, r.Module.Update.Version, bin.Package, pct))
+			}
+		}
+	}
+	return violations
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSymbolPackage(t *testing.T) {
+	pkgs := map[string]bool{
+		"gopkg.in/yaml.v3":                    true,
+		"github.com/a/b":                      true,
+		"github.com/a/b/internal/c":           true,
+		"example.com/gen":                     true,
+		"vendor/golang.org/x/net/http2/hpack": true,
+	}
+	tests := []struct {
+		name string
+		pkgs map[string]bool
+		want string
+	}{
+		{"runtime.mallocgc", pkgs, "runtime"},
+		{"main.main", pkgs, "main"},
+		{"type:.eq.github.com/a/b.T", pkgs, "<type>"},
+		{"go:itab.*os.File,io.Reader", pkgs, "<go>"},
+		{"gopkg.in/yaml.v3.(*decoder).unmarshal", pkgs, "gopkg.in/yaml.v3"},
+		{"gopkg.in/yaml.v3.Unmarshal", pkgs, "gopkg.in/yaml.v3"},
+		{"github.com/a/b.(*T).M", pkgs, "github.com/a/b"},
+		{"github.com/a/b/internal/c.F.func1", pkgs, "github.com/a/b/internal/c"},
+		{"example.com/gen.Map[go.shape.string,github.com/a/b/internal/c.T].Get", pkgs, "example.com/gen"},
+		{"example.com/gen.New[go.shape.struct { X []example.com/x/y.Z }]", pkgs, "example.com/gen"},
+		{"vendor/golang.org/x/net/http2/hpack.NewEncoder", pkgs, "vendor/golang.org/x/net/http2/hpack"},
+		// Without the package list the heuristic still handles the common
+		// cases, type arguments included.
+		{"github.com/a/b.(*T).M", nil, "github.com/a/b"},
+		{"example.com/gen.Map[go.shape.string,github.com/a/b/internal/c.T].Get", nil, "example.com/gen"},
+		{"sync/atomic.(*Int64).Add", nil, "sync/atomic"},
+		{"noDotSymbol", nil, "noDotSymbol"},
+	}
+	for _, tt := range tests {
+		if got := symbolPackage(tt.name, tt.pkgs); got != tt.want {
+			t.Errorf("symbolPackage(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDiffSymbolSizes(t *testing.T) {
+	before := map[string]int64{"runtime": 1000, "github.com/a/b": 300, "github.com/old/dep": 50, "fmt": 200}
+	after := map[string]int64{"runtime": 1010, "github.com/a/b": 100, "github.com/new/dep": 400, "fmt": 200}
+	want := []PackageSizeDelta{
+		{Package: "github.com/new/dep", After: 400},
+		{Package: "github.com/a/b", Before: 300, After: 100},
+		{Package: "github.com/old/dep", Before: 50},
+		{Package: "runtime", Before: 1000, After: 1010},
+	}
+	if got := diffSymbolSizes(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffSymbolSizes =\n%+v\nwant\n%+v", got, want)
+	}
+	if got := diffSymbolSizes(nil, after); got != nil {
+		t.Errorf("diffSymbolSizes without a breakdown = %+v, want nil", got)
+	}
+}
+
+func TestBinarySizeViolations(t *testing.T) {
+	dep := withUpdate(ModuleInfo{Path: "example.com/a", Version: "v1.0.0"}, "v1.1.0", nil)
+	reports := []BinarySizeReport{
+		{Module: dep, Binaries: []BinarySize{
+			{Package: "example.com/m/cmd/big", Before: 1000, After: 1100},
+			{Package: "example.com/m/cmd/small", Before: 1000, After: 1040},
+			{Package: "example.com/m/cmd/shrunk", Before: 1000, After: 500},
+			{Package: "example.com/m/cmd/new", Before: 0, After: 500},
+		}},
+		{Module: dep, Err: errors.New("build failed")},
+	}
+	want := []string{"example.com/a@v1.1.0: example.com/m/cmd/big grew by 10.00%"}
+	if got := binarySizeViolations(reports, 5); !reflect.DeepEqual(got, want) {
+		t.Errorf("violations = %q, want %q", got, want)
+	}
+	if got := binarySizeViolations(reports, 0); got != nil {
+		t.Errorf("violations with no threshold = %q, want none", got)
+	}
+	if got := binarySizeViolations(reports, 10); got != nil {
+		t.Errorf("violations at exactly the threshold = %q, want none", got)
+	}
+}
+
+func TestGrowthPercent(t *testing.T) {
+	tests := []struct {
+		before, after int64
+		want          float64
+	}{
+		{100, 150, 50},
+		{200, 100, -50},
+		{100, 100, 0},
+		{0, 100, 0},
+	}
+	for _, tt := range tests {
+		if got := growthPercent(tt.before, tt.after); got != tt.want {
+			t.Errorf("growthPercent(%d, %d) = %v, want %v", tt.before, tt.after, got, tt.want)
+		}
+	}
+}
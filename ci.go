@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/mod/modfile"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+type ciIssue struct {
+	Check    string
+	Module   string
+	Severity string
+	Message  string
+	File     string
+	Line     int
+}
+
+type codeQualityIssue struct {
+	Description string              `json:"description"`
+	CheckName   string              `json:"check_name"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    codeQualityLocation `json:"location"`
+}
+
+type codeQualityLocation struct {
+	Path  string `json:"path"`
+	Lines struct {
+		Begin int `json:"begin"`
+	} `json:"lines"`
+}
+
+var githubCommands = map[string]string{"info": "notice", "warning": "warning", "error": "error"}
+
+var codeQualitySeverities = map[string]string{"info": "info", "warning": "minor", "error": "major"}
+
+func ciIssues(report *Report, modFile *modfile.File, file string) []ciIssue {
+	lines := make(map[string]int)
+	for _, r := range modFile.Require {
+		lines[r.Mod.Path] = r.Syntax.Start.Line
+	}
+	moduleLine := 1
+	if modFile.Module != nil {
+		moduleLine = modFile.Module.Syntax.Start.Line
+	}
+	lineFor := func(path string) int {
+		if line, ok := lines[path]; ok {
+			return line
+		}
+		return moduleLine
+	}
+
+	var issues []ciIssue
+	add := func(check, module, severity, message string) {
+		issues = append(issues, ciIssue{Check: check, Module: module, Severity: severity, Message: message, File: file, Line: lineFor(module)})
+	}
+	now := time.Now()
+	for _, dep := range report.Updates {
+		if dep.Update != nil {
+			msg := fmt.Sprintf("%s %s can be updated to %s", dep.Path, dep.Version, dep.Update.Version)
+			if dep.Pin.Active(now) {
+				msg += " (" + dep.Pin.String() + ")"
+			}
+			if dep.Priority != nil {
+				msg += fmt.Sprintf(" (priority score %.2f)", dep.Priority.Score)
+			}
+			add("outdated", dep.Path, "info", msg)
+		}
+		if len(dep.Retracted) > 0 {
+			add("retracted", dep.Path, "warning", fmt.Sprintf("%s %s is retracted: %s", dep.Path, dep.Version, strings.Join(dep.Retracted, "; ")))
+		}
+		if dep.Deprecated != "" {
+			add("deprecated", dep.Path, "warning", fmt.Sprintf("%s is deprecated: %s", dep.Path, dep.Deprecated))
+		}
+	}
+	for _, f := range report.Findings {
+		add(f.Source, f.Module, f.Severity, f.Message)
+	}
+	return issues
+}
+
+func writeGitHubAnnotations(w io.Writer, issues []ciIssue) error {
+	for _, issue := range issues {
+		title := issue.Check
+		if issue.Module != "" {
+			title += ": " + issue.Module
+		}
+		_, err := fmt.Fprintf(w, "::%s file=%s,line=%d,title=%s::%s\n", githubCommands[issue.Severity],
+			escapeGitHubProperty(issue.File), issue.Line, escapeGitHubProperty(title), escapeGitHubData(issue.Message))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	githubDataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	githubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+func escapeGitHubData(s string) string {
+	return githubDataEscaper.Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return githubPropertyEscaper.Replace(s)
+}
+
+// writeCodeQuality writes a GitLab Code Quality report. Fingerprints hash
+// the check and module, not the message, so that an issue keeps its identity
+// across pipelines when only its details, such as the latest version, change.
+// Repeated check and module pairs are told apart by their order.
+func writeCodeQuality(w io.Writer, issues []ciIssue) error {
+	report := make([]codeQualityIssue, 0, len(issues))
+	seen := make(map[string]int)
+	for _, issue := range issues {
+		key := issue.Check + "\x00" + issue.Module
+		n := seen[key]
+		seen[key]++
+		if n > 0 {
+			key += "\x00" + strconv.Itoa(n)
+		}
+		sum := sha256.Sum256([]byte(key))
+		cq := codeQualityIssue{
+			Description: issue.Message,
+			CheckName:   issue.Check,
+			Fingerprint: hex.EncodeToString(sum[:16]),
+			Severity:    codeQualitySeverities[issue.Severity],
+			Location:    codeQualityLocation{Path: issue.File},
+		}
+		cq.Location.Lines.Begin = issue.Line
+		report = append(report, cq)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+func writeReport(format string, tmpl *template.Template, report *Report, root, goModPath string) error {
+	if format == "text" {
+		return printResults(os.Stdout, tmpl, report)
+	}
+	modFile, err := readRequirements(goModPath)
+	if err != nil {
+		return err
+	}
+	file, err := filepath.Rel(root, goModPath)
+	if err != nil {
+		return err
+	}
+	issues := ciIssues(report, modFile, filepath.ToSlash(file))
+	if format == "github" {
+		return writeGitHubAnnotations(os.Stdout, issues)
+	}
+	return writeCodeQuality(os.Stdout, issues)
+}
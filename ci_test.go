@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func codeQualityFingerprints(t *testing.T, issues []ciIssue) []string {
+	t.Helper()
+	var sb strings.Builder
+	if err := writeCodeQuality(&sb, issues); err != nil {
+		t.Fatal(err)
+	}
+	var report []codeQualityIssue
+	if err := json.Unmarshal([]byte(sb.String()), &report); err != nil {
+		t.Fatal(err)
+	}
+	var fingerprints []string
+	for _, cq := range report {
+		fingerprints = append(fingerprints, cq.Fingerprint)
+	}
+	return fingerprints
+}
+
+func TestCodeQualityFingerprint(t *testing.T) {
+	before := codeQualityFingerprints(t, []ciIssue{
+		{Check: "outdated", Module: "example.com/a", Severity: "info", Message: "example.com/a v1.0.0 can be updated to v1.1.0"},
+		{Check: "deny", Severity: "warning", Message: "first"},
+		{Check: "deny", Severity: "warning", Message: "second"},
+	})
+	after := codeQualityFingerprints(t, []ciIssue{
+		{Check: "outdated", Module: "example.com/a", Severity: "info", Message: "example.com/a v1.0.0 can be updated to v1.2.0"},
+		{Check: "deny", Severity: "warning", Message: "first"},
+		{Check: "deny", Severity: "warning", Message: "second"},
+	})
+	if before[0] != after[0] {
+		t.Error("fingerprint changed with a newer upstream release")
+	}
+	if before[1] == before[2] {
+		t.Error("repeated check and module share a fingerprint")
+	}
+	if before[1] != after[1] || before[2] != after[2] {
+		t.Error("fingerprints of unchanged issues differ between runs")
+	}
+}
+
+func TestWriteGitHubAnnotations(t *testing.T) {
+	var sb strings.Builder
+	err := writeGitHubAnnotations(&sb, []ciIssue{
+		{Check: "deprecated", Module: "example.com/a", Severity: "warning", Message: "100% gone\nuse b", File: "go.mod", Line: 5},
+		{Check: "plugin", Severity: "info", Message: "note", File: "go.mod", Line: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "::warning file=go.mod,line=5,title=deprecated%3A example.com/a::100%25 gone%0Ause b\n" +
+		"::notice file=go.mod,line=1,title=plugin::note\n"
+	if sb.String() != want {
+		t.Errorf("annotations =\n%s\nwant\n%s", sb.String(), want)
+	}
+}
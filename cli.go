@@ -0,0 +1,354 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const envPrefix = "DEPANALYSIS_"
+
+// A command defines its flags on fs and returns the function that runs it
+// once the flags have been parsed.
+type command struct {
+	name    string
+	aliases []string
+	args    string
+	summary string
+	define  func(fs *flag.FlagSet) func() error
+}
+
+// usageError reports invalid arguments. main prints its message, if any,
+// followed by the usage of the command and exits with status 2.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	if e.msg == "" {
+		return "invalid arguments"
+	}
+	return e.msg
+}
+
+var errUsage = &usageError{}
+
+func usageErrorf(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// exitStatus ends a command with a non-zero status without printing an
+// error, for commands whose output already explains the failure.
+type exitStatus int
+
+func (e exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+var commands []*command
+
+var progName = filepath.Base(os.Args[0])
+
+var globals struct {
+	trace traceConfig
+	quiet bool
+}
+
+func init() {
+	commands = []*command{
+		{name: "analyze", args: "<git-repo-url>", summary: "report available dependency updates of a repository", define: analyzeCommand},
+		{name: "graph", args: "<git-repo-url>", summary: "print the module requirement graph", define: graphCommand},
+		{name: "why", args: "<git-repo-url> <module>", summary: "show the shortest requirement chain to a module", define: whyCommand},
+		{name: "diff", aliases: []string{"depdiff"}, args: "<git-repo-url> <module>", summary: "diff a dependency's current version against its update", define: depDiffCommand},
+		{name: "gobump", args: "<git-repo-url> <go-version>", summary: "show the impact of raising the go directive", define: goBumpCommand},
+		{name: "linkname", args: "<git-repo-url>", summary: "check go:linkname directives against the linker rules of a Go version", define: linknameCommand},
+		{name: "versions", args: "<module>", summary: "list the published versions of a module", define: versionsCommand},
+		{name: "upgrade", args: "[<module>...]", summary: "upgrade dependencies of a local module, honoring pins", define: upgradeCommand},
+		{name: "index", args: "[<git-repo-url>...]", summary: "build a reverse-dependency index over a fleet of repositories", define: indexCommand},
+		{name: "dependents", args: "<module>", summary: "list indexed repositories that require a module", define: dependentsCommand},
+		{name: "rollout", args: "[<module>@<version>]", summary: "plan the order in which internal modules are updated", define: rolloutCommand},
+		{name: "sumcheck", args: "[<git-repo-url>...]", summary: "check go.sum hashes for consistency across repositories", define: sumCheckCommand},
+		{name: "merge-driver", args: "<base> <ours> <theirs> [<path>]", summary: "merge go.mod or go.sum as a git merge driver", define: mergeDriverCommand},
+		{name: "lsp", summary: "serve go.mod diagnostics over the Language Server Protocol", define: lspCommand},
+		{name: "completion", args: "bash|zsh|fish", summary: "print a shell completion script", define: completionCommand},
+		{name: "help", args: "[<command>]", summary: "show help for a command", define: helpCommand},
+	}
+}
+
+func lookupCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+		for _, alias := range cmd.aliases {
+			if alias == name {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+func addGlobalFlags(fs *flag.FlagSet) {
+	addTraceFlags(fs, &globals.trace)
+	fs.BoolVar(&globals.quiet, "quiet", false, "suppress git clone progress output")
+}
+
+// legacyEnvNames maps "<command> <flag>" to the environment variable that
+// set the flag before variables were named per command. The old name is
+// still read, with a warning, when the new one is unset.
+var legacyEnvNames = map[string]string{
+	"analyze plugin-dir": envPrefix + "PLUGIN_DIR",
+}
+
+// newCommandFlags returns the flag set of cmd, with the global flags added
+// and defaults taken from environment variables: DEPANALYSIS_<COMMAND>_<FLAG>
+// for the flags of the command and DEPANALYSIS_<FLAG> for global flags, so
+// that a flag such as -format can have a different default per command.
+// DEPANALYSIS_PLUGIN_DIR is still accepted for analyze -plugin-dir.
+func newCommandFlags(cmd *command) (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	run := cmd.define(fs)
+	own := make(map[string]bool)
+	fs.VisitAll(func(f *flag.Flag) { own[f.Name] = true })
+	addGlobalFlags(fs)
+	fs.VisitAll(func(f *flag.Flag) {
+		name := envName("", f.Name)
+		if own[f.Name] {
+			name = envName(cmd.name, f.Name)
+		}
+		v, ok := os.LookupEnv(name)
+		if legacy := legacyEnvNames[cmd.name+" "+f.Name]; !ok && legacy != "" && own[f.Name] {
+			if v, ok = os.LookupEnv(legacy); ok {
+				fmt.Fprintf(os.Stderr, "warning: %s is deprecated, use %s\n", legacy, name)
+				name = legacy
+			}
+		}
+		if ok {
+			if err := fs.Set(f.Name, v); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: ignoring %s: %v\n", name, err)
+				return
+			}
+			f.DefValue = v
+		}
+	})
+	fs.Usage = func() { commandUsage(fs.Output(), cmd, fs, own) }
+	return fs, run
+}
+
+// envName returns the environment variable holding the default of a flag
+// of the named command, or of a global flag if command is empty.
+func envName(command, flagName string) string {
+	name := flagName
+	if command != "" {
+		name = command + "_" + flagName
+	}
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// parseGlobalFlags parses the global flags given before the command name and
+// returns their values by name. If args starts with anything else, such as
+// the flags of the implicit analyze command, it leaves args for the command
+// to parse.
+func parseGlobalFlags(args []string) (map[string]string, []string) {
+	root := flag.NewFlagSet(progName, flag.ContinueOnError)
+	root.SetOutput(io.Discard)
+	addGlobalFlags(root)
+	if err := root.Parse(args); err != nil {
+		return nil, args
+	}
+	values := make(map[string]string)
+	root.Visit(func(f *flag.Flag) { values[f.Name] = f.Value.String() })
+	return values, root.Args()
+}
+
+func commandUsage(w io.Writer, cmd *command, fs *flag.FlagSet, own map[string]bool) {
+	fmt.Fprintf(w, "Usage: %s %s [flags] %s\n\n", progName, cmd.name, cmd.args)
+	fmt.Fprintf(w, "%s%s.\n", strings.ToUpper(cmd.summary[:1]), cmd.summary[1:])
+	if len(cmd.aliases) > 0 {
+		fmt.Fprintf(w, "Aliases: %s\n", strings.Join(cmd.aliases, ", "))
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	fs.PrintDefaults()
+	example := ""
+	fs.VisitAll(func(f *flag.Flag) {
+		if example == "" && own[f.Name] {
+			example = f.Name
+		}
+	})
+	fmt.Fprintf(w, "\nEvery flag can also be set with an environment variable, ")
+	if example != "" {
+		fmt.Fprintf(w, "e.g. %s for -%s, or %s for the global -quiet.\n", envName(cmd.name, example), example, envName("", "quiet"))
+	} else {
+		fmt.Fprintf(w, "e.g. %s for the global -quiet.\n", envName("", "quiet"))
+	}
+	fmt.Fprintf(w, "Global flags can also be given before the command name.\n")
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s [global flags] <command> [flags] [arguments]\n", progName)
+	fmt.Fprintf(w, "       %s [analyze flags] <git-repo-url>\n\nCommands:\n", progName)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-11s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun '%s help <command>' for the flags of a command.\n", progName)
+}
+
+func main() {
+	args := os.Args[1:]
+	if len(args) == 0 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	if args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		usage(os.Stdout)
+		return
+	}
+	globalFlags, args := parseGlobalFlags(args)
+	cmd := lookupCommand("analyze")
+	if len(args) > 0 {
+		if c := lookupCommand(args[0]); c != nil {
+			cmd, args = c, args[1:]
+		}
+	}
+
+	fs, run := newCommandFlags(cmd)
+	for name, value := range globalFlags {
+		if err := fs.Set(name, value); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: ignoring -%s: %v\n", name, err)
+		}
+	}
+	fs.Parse(args)
+	globals.trace.start()
+	root := startSpan(cmd.name, "command.args", strings.Join(fs.Args(), " "))
+	err := run()
+	root.End(err)
+	finishTracing()
+
+	var usageErr *usageError
+	var status exitStatus
+	switch {
+	case err == nil:
+	case errors.As(err, &usageErr):
+		if usageErr.msg != "" {
+			fmt.Fprintf(os.Stderr, "%s.\n\n", capitalize(usageErr.msg))
+		}
+		fs.Usage()
+		os.Exit(2)
+	case errors.As(err, &status):
+		os.Exit(int(status))
+	default:
+		log.Fatal(capitalize(err.Error()))
+	}
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func helpCommand(fs *flag.FlagSet) func() error {
+	return func() error {
+		if fs.NArg() == 0 {
+			usage(os.Stdout)
+			return nil
+		}
+		cmd := lookupCommand(fs.Arg(0))
+		if cmd == nil {
+			fmt.Fprintf(os.Stderr, "Unknown command %q.\n\n", fs.Arg(0))
+			usage(os.Stderr)
+			return exitStatus(2)
+		}
+		cmdFlags, _ := newCommandFlags(cmd)
+		cmdFlags.SetOutput(os.Stdout)
+		cmdFlags.Usage()
+		return nil
+	}
+}
+
+func completionCommand(fs *flag.FlagSet) func() error {
+	name := fs.String("name", progName, "command name to complete")
+	return func() error {
+		if fs.NArg() != 1 {
+			return errUsage
+		}
+		switch fs.Arg(0) {
+		case "bash":
+			fmt.Print(bashCompletion(*name))
+		case "zsh":
+			fmt.Print("autoload -U +X bashcompinit && bashcompinit\n" + bashCompletion(*name))
+		case "fish":
+			fmt.Print(fishCompletion(*name))
+		default:
+			return errUsage
+		}
+		return nil
+	}
+}
+
+func commandFlagNames(cmd *command) []string {
+	fs, _ := newCommandFlags(cmd)
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+	sort.Strings(names)
+	return names
+}
+
+func bashCompletion(name string) string {
+	fn := "_" + strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' {
+			return '_'
+		}
+		return r
+	}, name)
+	var b strings.Builder
+	var names, analyzeFlags []string
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" flags\n")
+	b.WriteString("\tcase \"${COMP_WORDS[1]}\" in\n")
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+		var flags []string
+		for _, f := range commandFlagNames(cmd) {
+			flags = append(flags, "-"+f)
+		}
+		pattern := strings.Join(append([]string{cmd.name}, cmd.aliases...), "|")
+		fmt.Fprintf(&b, "\t%s) flags=%q ;;\n", pattern, strings.Join(flags, " "))
+		if cmd.name == "analyze" {
+			analyzeFlags = flags
+		}
+	}
+	fmt.Fprintf(&b, "\t*) flags=%q ;;\n", strings.Join(analyzeFlags, " "))
+	b.WriteString("\tesac\n")
+	b.WriteString("\tif [ \"$COMP_CWORD\" -eq 1 ] && [[ \"$cur\" != -* ]]; then\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	b.WriteString("\telif [[ \"$cur\" == -* ]]; then\n")
+	b.WriteString("\t\tCOMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n")
+	b.WriteString("\telse\n")
+	b.WriteString("\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n")
+	b.WriteString("\tfi\n}\n")
+	fmt.Fprintf(&b, "complete -F %s %s\n", fn, name)
+	return b.String()
+}
+
+func fishCompletion(name string) string {
+	var b strings.Builder
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "complete -c %s -f -n '__fish_use_subcommand' -a %s -d %q\n", name, cmd.name, cmd.summary)
+	}
+	for _, cmd := range commands {
+		for _, f := range commandFlagNames(cmd) {
+			fmt.Fprintf(&b, "complete -c %s -n '__fish_seen_subcommand_from %s' -o %s\n",
+				name, strings.Join(append([]string{cmd.name}, cmd.aliases...), " "), f)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnvName(t *testing.T) {
+	tests := []struct{ command, flag, want string }{
+		{"", "quiet", "DEPANALYSIS_QUIET"},
+		{"", "trace-endpoint", "DEPANALYSIS_TRACE_ENDPOINT"},
+		{"graph", "format", "DEPANALYSIS_GRAPH_FORMAT"},
+		{"merge-driver", "kind", "DEPANALYSIS_MERGE_DRIVER_KIND"},
+	}
+	for _, tt := range tests {
+		if got := envName(tt.command, tt.flag); got != tt.want {
+			t.Errorf("envName(%q, %q) = %s, want %s", tt.command, tt.flag, got, tt.want)
+		}
+	}
+}
+
+func TestCommandFlagsFromEnv(t *testing.T) {
+	t.Setenv("DEPANALYSIS_GRAPH_FORMAT", "dot")
+	t.Setenv("DEPANALYSIS_FORMAT", "json")
+	t.Setenv("DEPANALYSIS_QUIET", "true")
+	defer func() { globals.quiet = false }()
+
+	graph, _ := newCommandFlags(lookupCommand("graph"))
+	if got := graph.Lookup("format").Value.String(); got != "dot" {
+		t.Errorf("graph -format = %s, want dot", got)
+	}
+	analyze, _ := newCommandFlags(lookupCommand("analyze"))
+	if got := analyze.Lookup("format").Value.String(); got != "text" {
+		t.Errorf("analyze -format = %s, want the default text", got)
+	}
+	if !globals.quiet {
+		t.Error("DEPANALYSIS_QUIET did not set -quiet")
+	}
+}
+
+func TestCommandFlagsLegacyEnv(t *testing.T) {
+	t.Setenv("DEPANALYSIS_PLUGIN_DIR", "/old")
+	analyze, _ := newCommandFlags(lookupCommand("analyze"))
+	if got := analyze.Lookup("plugin-dir").Value.String(); got != "/old" {
+		t.Errorf("analyze -plugin-dir = %s, want /old from DEPANALYSIS_PLUGIN_DIR", got)
+	}
+
+	t.Setenv("DEPANALYSIS_ANALYZE_PLUGIN_DIR", "/new")
+	analyze, _ = newCommandFlags(lookupCommand("analyze"))
+	if got := analyze.Lookup("plugin-dir").Value.String(); got != "/new" {
+		t.Errorf("analyze -plugin-dir = %s, want /new from DEPANALYSIS_ANALYZE_PLUGIN_DIR", got)
+	}
+}
+
+func TestParseGlobalFlags(t *testing.T) {
+	tests := []struct {
+		args       []string
+		wantValues map[string]string
+		wantRest   []string
+	}{
+		{[]string{"-quiet", "analyze", "url"}, map[string]string{"quiet": "true"}, []string{"analyze", "url"}},
+		{[]string{"-trace-file", "t.json", "graph", "-format", "dot", "url"}, map[string]string{"trace-file": "t.json"}, []string{"graph", "-format", "dot", "url"}},
+		{[]string{"graph", "-quiet", "url"}, map[string]string{}, []string{"graph", "-quiet", "url"}},
+		{[]string{"-quiet", "-format", "github", "url"}, nil, []string{"-quiet", "-format", "github", "url"}},
+	}
+	saved := globals
+	defer func() { globals = saved }()
+	for _, tt := range tests {
+		values, rest := parseGlobalFlags(tt.args)
+		if !reflect.DeepEqual(values, tt.wantValues) || !reflect.DeepEqual(rest, tt.wantRest) {
+			t.Errorf("parseGlobalFlags(%q) = %v, %q, want %v, %q", tt.args, values, rest, tt.wantValues, tt.wantRest)
+		}
+	}
+}
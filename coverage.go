@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type CallSiteCoverage struct {
+	Sites   int `json:"Sites"`
+	Covered int `json:"Covered"`
+}
+
+func (c CallSiteCoverage) Percent() float64 {
+	if c.Sites == 0 {
+		return 0
+	}
+	return float64(c.Covered) / float64(c.Sites) * 100
+}
+
+type coverBlock struct {
+	startLine, startCol int
+	endLine, endCol     int
+	count               int
+}
+
+func (b coverBlock) contains(line, col int) bool {
+	if line < b.startLine || line > b.endLine {
+		return false
+	}
+	if line == b.startLine && col < b.startCol {
+		return false
+	}
+	if line == b.endLine && col > b.endCol {
+		return false
+	}
+	return true
+}
+
+type callSite struct {
+	module string
+	file   string
+	line   int
+	col    int
+}
+
+func analyzeCallSiteCoverage(modDir string) (map[string]*CallSiteCoverage, error) {
+	modules, err := listModules(modDir, false)
+	if err != nil {
+		return nil, err
+	}
+	sites, err := findDependencyCallSites(modDir, modules)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := runCoverProfile(modDir)
+	if err != nil {
+		return nil, err
+	}
+
+	coverage := make(map[string]*CallSiteCoverage)
+	for _, site := range sites {
+		c := coverage[site.module]
+		if c == nil {
+			c = &CallSiteCoverage{}
+			coverage[site.module] = c
+		}
+		c.Sites++
+		for _, b := range profile[site.file] {
+			if b.count > 0 && b.contains(site.line, site.col) {
+				c.Covered++
+				break
+			}
+		}
+	}
+	return coverage, nil
+}
+
+func runCoverProfile(modDir string) (map[string][]coverBlock, error) {
+	f, err := os.CreateTemp("", "go-dep-cover-*.out")
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	_, testErr := runGo(modDir, "test", "-mod=mod", "-coverpkg=./...", "-coverprofile="+f.Name(), "./...")
+	profile, err := parseCoverProfile(f.Name())
+	if err != nil || len(profile) == 0 {
+		if testErr != nil {
+			return nil, testErr
+		}
+		return nil, err
+	}
+	if testErr != nil {
+		fmt.Fprintf(os.Stderr, "warning: some tests failed, coverage may be incomplete: %v\n", testErr)
+	}
+	return profile, nil
+}
+
+func parseCoverProfile(name string) (map[string][]coverBlock, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	profile := make(map[string][]coverBlock)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "mode:") || line == "" {
+			continue
+		}
+		colon := strings.LastIndex(line, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("malformed coverage line %q", line)
+		}
+		var b coverBlock
+		var stmts int
+		if _, err := fmt.Sscanf(line[colon+1:], "%d.%d,%d.%d %d %d",
+			&b.startLine, &b.startCol, &b.endLine, &b.endCol, &stmts, &b.count); err != nil {
+			return nil, fmt.Errorf("malformed coverage line %q: %v", line, err)
+		}
+		file := line[:colon]
+		profile[file] = append(profile[file], b)
+	}
+	return profile, scanner.Err()
+}
+
+func findDependencyCallSites(modDir string, modules []ModuleInfo) ([]callSite, error) {
+	out, err := runGo(modDir, "list", "-deps", "-f", "{{.ImportPath}}\t{{.Name}}\t{{with .Module}}{{.Path}}{{end}}\t{{.Dir}}\t{{join .GoFiles \",\"}}", "./...")
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string)
+	type localPkg struct {
+		importPath, dir string
+		files           []string
+	}
+	var locals []localPkg
+	var mainModule string
+	for _, m := range modules {
+		if m.Main {
+			mainModule = m.Path
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 {
+			continue
+		}
+		names[fields[0]] = fields[1]
+		if fields[2] == mainModule && fields[4] != "" {
+			locals = append(locals, localPkg{fields[0], fields[3], strings.Split(fields[4], ",")})
+		}
+	}
+
+	var sites []callSite
+	fset := token.NewFileSet()
+	for _, pkg := range locals {
+		for _, name := range pkg.files {
+			file, err := parser.ParseFile(fset, filepath.Join(pkg.dir, name), nil, 0)
+			if err != nil {
+				return nil, err
+			}
+			imports := make(map[string]string)
+			for _, imp := range file.Imports {
+				p, err := strconv.Unquote(imp.Path.Value)
+				if err != nil {
+					continue
+				}
+				mod := moduleForPackage(p, modules)
+				if mod == "" {
+					continue
+				}
+				local := names[p]
+				if imp.Name != nil {
+					local = imp.Name.Name
+				}
+				if local == "" || local == "_" || local == "." {
+					continue
+				}
+				imports[local] = mod
+			}
+			if len(imports) == 0 {
+				continue
+			}
+			profileName := path.Join(pkg.importPath, name)
+			visit := func(n ast.Node) bool {
+				sel, ok := n.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				id, ok := sel.X.(*ast.Ident)
+				if !ok || id.Obj != nil {
+					return true
+				}
+				if mod, ok := imports[id.Name]; ok {
+					pos := fset.Position(sel.Pos())
+					sites = append(sites, callSite{module: mod, file: profileName, line: pos.Line, col: pos.Column})
+				}
+				return true
+			}
+			// Only function bodies hold statements that coverage counts;
+			// references in signatures and top-level declarations can
+			// never be covered.
+			for _, decl := range file.Decls {
+				if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+					ast.Inspect(fn.Body, visit)
+				}
+			}
+		}
+	}
+	return sites, nil
+}
+
+func moduleForPackage(importPath string, modules []ModuleInfo) string {
+	var best string
+	for _, m := range modules {
+		if m.Main {
+			continue
+		}
+		if (importPath == m.Path || strings.HasPrefix(importPath, m.Path+"/")) && len(m.Path) > len(best) {
+			best = m.Path
+		}
+	}
+	return best
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCoverBlockContains(t *testing.T) {
+	b := coverBlock{startLine: 10, startCol: 5, endLine: 12, endCol: 3}
+	tests := []struct {
+		line, col int
+		want      bool
+	}{
+		{10, 5, true},
+		{10, 4, false},
+		{11, 1, true},
+		{12, 3, true},
+		{12, 4, false},
+		{9, 80, false},
+		{13, 1, false},
+	}
+	for _, tt := range tests {
+		if got := b.contains(tt.line, tt.col); got != tt.want {
+			t.Errorf("contains(%d, %d) = %v, want %v", tt.line, tt.col, got, tt.want)
+		}
+	}
+}
+
+func TestParseCoverProfile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "cover.out")
+	data := "mode: set\nexample.com/m/a.go:3.14,5.2 1 1\nexample.com/m/a.go:7.1,8.2 2 0\nexample.com/m/b/b.go:1.1,1.20 1 1\n"
+	if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	profile, err := parseCoverProfile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := profile["example.com/m/a.go"]
+	if len(a) != 2 || a[0] != (coverBlock{3, 14, 5, 2, 1}) || a[1].count != 0 {
+		t.Errorf("a.go blocks = %+v", a)
+	}
+	if len(profile["example.com/m/b/b.go"]) != 1 {
+		t.Errorf("b.go blocks = %+v", profile["example.com/m/b/b.go"])
+	}
+
+	if err := os.WriteFile(name, []byte("mode: set\nno colon here\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseCoverProfile(name); err == nil {
+		t.Error("malformed profile parsed without error")
+	}
+}
+
+func TestModuleForPackage(t *testing.T) {
+	modules := []ModuleInfo{
+		{Path: "example.com/main", Main: true},
+		{Path: "github.com/a/b"},
+		{Path: "github.com/a/b/v2"},
+		{Path: "github.com/a/b/sub"},
+	}
+	tests := map[string]string{
+		"github.com/a/b":           "github.com/a/b",
+		"github.com/a/b/pkg":       "github.com/a/b",
+		"github.com/a/b/v2/pkg":    "github.com/a/b/v2",
+		"github.com/a/b/sub/inner": "github.com/a/b/sub",
+		"github.com/a/bc":          "",
+		"example.com/main/pkg":     "",
+		"fmt":                      "",
+	}
+	for pkg, want := range tests {
+		if got := moduleForPackage(pkg, modules); got != want {
+			t.Errorf("moduleForPackage(%q) = %q, want %q", pkg, got, want)
+		}
+	}
+}
+
+func TestFindDependencyCallSites(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":     "module example.com/m\n\ngo 1.21\n\nrequire example.com/dep v0.0.0\n\nreplace example.com/dep => ./dep\n",
+		"dep/go.mod": "module example.com/dep\n\ngo 1.21\n",
+		"dep/dep.go": "package dep\n\ntype T struct{}\n\nfunc New() T { return T{} }\n\nfunc Use(T) {}\n",
+		"m.go": `package m
+
+import (
+	"fmt"
+
+	d "example.com/dep"
+)
+
+var global = d.New()
+
+type W struct{ t d.T }
+
+func F(x d.T) d.T {
+	v := d.New()
+	func() { d.Use(v) }()
+	fmt.Println(v)
+	var y d.T
+	return y
+}
+`,
+	})
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Setenv("GOPROXY", "off")
+	modules, err := listModules(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sites, err := findDependencyCallSites(dir, modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []callSite{
+		{module: "example.com/dep", file: "example.com/m/m.go", line: 14, col: 7},
+		{module: "example.com/dep", file: "example.com/m/m.go", line: 15, col: 11},
+		{module: "example.com/dep", file: "example.com/m/m.go", line: 17, col: 8},
+	}
+	if !reflect.DeepEqual(sites, want) {
+		t.Errorf("call sites = %+v, want %+v", sites, want)
+	}
+}
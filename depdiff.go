@@ -0,0 +1,178 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type moduleDownload struct {
+	Path    string `json:"Path"`
+	Version string `json:"Version"`
+	Zip     string `json:"Zip"`
+	Dir     string `json:"Dir"`
+	GoMod   string `json:"GoMod"`
+	Error   string `json:"Error"`
+}
+
+func downloadModule(modPath, version string) (info moduleDownload, err error) {
+	sp := startSpan("proxy lookup", "module.path", modPath, "module.version", version)
+	defer func() { sp.End(err) }()
+	out, err := runGo(os.TempDir(), "mod", "download", "-json", modPath+"@"+version)
+	if jsonErr := json.Unmarshal(out, &info); jsonErr != nil && err == nil {
+		err = jsonErr
+	}
+	if info.Error != "" {
+		return info, fmt.Errorf("%s@%s: %s", modPath, version, info.Error)
+	}
+	return info, err
+}
+
+func moduleZipFiles(modPath, version string, all bool) (map[string][]byte, error) {
+	info, err := downloadModule(modPath, version)
+	if err != nil {
+		return nil, err
+	}
+	r, err := zip.OpenReader(info.Zip)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	prefix := modPath + "@" + version + "/"
+	files := make(map[string][]byte)
+	for _, f := range r.File {
+		name := strings.TrimPrefix(f.Name, prefix)
+		if strings.HasSuffix(name, "/") {
+			continue
+		}
+		if !all && (path.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go")) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[name] = data
+	}
+	return files, nil
+}
+
+type fileDiff struct {
+	name string
+	ops  []diffOp
+}
+
+func diffModuleVersions(modPath, oldVersion, newVersion string, all bool) ([]fileDiff, DiffStat, error) {
+	var stat DiffStat
+	oldFiles, err := moduleZipFiles(modPath, oldVersion, all)
+	if err != nil {
+		return nil, stat, err
+	}
+	newFiles, err := moduleZipFiles(modPath, newVersion, all)
+	if err != nil {
+		return nil, stat, err
+	}
+
+	names := make(map[string]bool)
+	for name := range oldFiles {
+		names[name] = true
+	}
+	for name := range newFiles {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var diffs []fileDiff
+	for _, name := range sorted {
+		ops := diffLines(splitLines(oldFiles[name]), splitLines(newFiles[name]))
+		before := stat
+		stat.add(ops)
+		if stat.Files != before.Files {
+			diffs = append(diffs, fileDiff{name: name, ops: ops})
+		}
+	}
+	return diffs, stat, nil
+}
+
+func upstreamChurn(deps []ModuleInfo) {
+	for i := range deps {
+		if deps[i].Update == nil {
+			continue
+		}
+		_, stat, err := diffModuleVersions(deps[i].Path, deps[i].Version, deps[i].Update.Version, false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: could not diff %s: %v\n", deps[i].Path, err)
+			continue
+		}
+		deps[i].Churn = &stat
+	}
+}
+
+func depDiffCommand(fs *flag.FlagSet) func() error {
+	all := fs.Bool("all", false, "include test files and non-Go files")
+	statOnly := fs.Bool("stat", false, "print only the diff-stat summary")
+	return func() error { return runDepDiff(fs, *all, *statOnly) }
+}
+
+func runDepDiff(fs *flag.FlagSet, all, statOnly bool) error {
+	if fs.NArg() != 2 {
+		return errUsage
+	}
+
+	dir, goModPath, err := checkoutRepo(fs.Arg(0))
+	if dir != "" {
+		defer os.RemoveAll(dir)
+	}
+	if err != nil {
+		return fmt.Errorf("error preparing repository: %v", err)
+	}
+
+	deps, err := getDependencies(filepath.Dir(goModPath))
+	if err != nil {
+		return fmt.Errorf("error getting dependencies: %v", err)
+	}
+	var dep *ModuleInfo
+	for i := range deps {
+		if deps[i].Path == fs.Arg(1) {
+			dep = &deps[i]
+		}
+	}
+	if dep == nil {
+		return fmt.Errorf("module %s is not required or has no available update", fs.Arg(1))
+	}
+
+	diffs, stat, err := diffModuleVersions(dep.Path, dep.Version, dep.Update.Version, all)
+	if err != nil {
+		return fmt.Errorf("error diffing %s: %v", dep.Path, err)
+	}
+	if !statOnly {
+		for _, d := range diffs {
+			fmt.Printf("diff %s@%s/%s %s@%s/%s\n", dep.Path, dep.Version, d.name, dep.Path, dep.Update.Version, d.name)
+			fmt.Print(unifiedDiff("a/"+d.name, "b/"+d.name, d.ops))
+		}
+	}
+	for _, d := range diffs {
+		var s DiffStat
+		s.add(d.ops)
+		fmt.Printf(" %s | +%d -%d\n", d.name, s.Added, s.Removed)
+	}
+	fmt.Printf(" %s\n", stat)
+	return nil
+}
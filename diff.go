@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	diffContext  = 3
+	maxDiffEdits = 4000
+)
+
+type diffOp struct {
+	kind byte
+	line string
+}
+
+type DiffStat struct {
+	Files   int `json:"Files"`
+	Added   int `json:"Added"`
+	Removed int `json:"Removed"`
+}
+
+func (s *DiffStat) add(ops []diffOp) {
+	changed := false
+	for _, op := range ops {
+		switch op.kind {
+		case '+':
+			s.Added++
+			changed = true
+		case '-':
+			s.Removed++
+			changed = true
+		}
+	}
+	if changed {
+		s.Files++
+	}
+}
+
+func (s DiffStat) String() string {
+	return fmt.Sprintf("%d files changed, %d insertions(+), %d deletions(-)", s.Files, s.Added, s.Removed)
+}
+
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a shortest edit script with the linear-space variant
+// of Myers' algorithm: it finds the middle snake of the edit graph and
+// recurses on both halves, so memory stays proportional to the input.
+// Scripts that would need more than about maxDiffEdits edits degrade to
+// replacing the whole file.
+func diffLines(a, b []string) []diffOp {
+	if len(a)+len(b) == 0 {
+		return nil
+	}
+	size := 2*((len(a)+len(b)+1)/2) + 3
+	d := &myersDiff{a: a, b: b, vf: make([]int, size), vb: make([]int, size), off: size / 2}
+	if !d.diff(0, len(a), 0, len(b), (maxDiffEdits+1)/2) {
+		ops := make([]diffOp, 0, len(a)+len(b))
+		for _, l := range a {
+			ops = append(ops, diffOp{'-', l})
+		}
+		for _, l := range b {
+			ops = append(ops, diffOp{'+', l})
+		}
+		return ops
+	}
+	return d.ops
+}
+
+type myersDiff struct {
+	a, b   []string
+	vf, vb []int
+	off    int
+	ops    []diffOp
+}
+
+func (d *myersDiff) emit(kind byte, lines []string) {
+	for _, l := range lines {
+		d.ops = append(d.ops, diffOp{kind, l})
+	}
+}
+
+// diff appends the edit script turning a[a0:a1] into b[b0:b1]. A limit of
+// zero or more bounds the search for the middle snake; diff reports false
+// if the script would be longer, without emitting anything.
+func (d *myersDiff) diff(a0, a1, b0, b1, limit int) bool {
+	prefix := 0
+	for a0+prefix < a1 && b0+prefix < b1 && d.a[a0+prefix] == d.b[b0+prefix] {
+		prefix++
+	}
+	suffix := 0
+	for a1-suffix > a0+prefix && b1-suffix > b0+prefix && d.a[a1-suffix-1] == d.b[b1-suffix-1] {
+		suffix++
+	}
+	var x0, y0, x1, y1 int
+	inner := a0+prefix < a1-suffix && b0+prefix < b1-suffix
+	if inner {
+		var ok bool
+		x0, y0, x1, y1, ok = d.middleSnake(a0+prefix, a1-suffix, b0+prefix, b1-suffix, limit)
+		if !ok {
+			return false
+		}
+	}
+	d.emit(' ', d.a[a0:a0+prefix])
+	a0, b0, a1, b1 = a0+prefix, b0+prefix, a1-suffix, b1-suffix
+	switch {
+	case !inner:
+		d.emit('-', d.a[a0:a1])
+		d.emit('+', d.b[b0:b1])
+	default:
+		d.diff(a0, x0, b0, y0, -1)
+		d.emit(' ', d.a[x0:x1])
+		d.diff(x1, a1, y1, b1, -1)
+	}
+	d.emit(' ', d.a[a1:a1+suffix])
+	return true
+}
+
+// middleSnake runs the forward and reverse searches of a[a0:a1] against
+// b[b0:b1] until they overlap and returns the snake where they meet, from
+// (x0, y0) to (x1, y1) in absolute indexes.
+func (d *myersDiff) middleSnake(a0, a1, b0, b1, limit int) (x0, y0, x1, y1 int, ok bool) {
+	a, b := d.a[a0:a1], d.b[b0:b1]
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	vf, vb, off := d.vf, d.vb, d.off
+	vf[off+1], vb[off+1] = 0, 0
+	for step := 0; step <= (n+m+1)/2; step++ {
+		if limit >= 0 && step > limit {
+			return 0, 0, 0, 0, false
+		}
+		for k := -step; k <= step; k += 2 {
+			var x int
+			if k == -step || (k != step && vf[off+k-1] < vf[off+k+1]) {
+				x = vf[off+k+1]
+			} else {
+				x = vf[off+k-1] + 1
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			vf[off+k] = x
+			if kr := delta - k; odd && kr >= -(step-1) && kr <= step-1 && x >= n-vb[off+kr] {
+				return a0 + sx, b0 + sy, a0 + x, b0 + y, true
+			}
+		}
+		for k := -step; k <= step; k += 2 {
+			var x int
+			if k == -step || (k != step && vb[off+k-1] < vb[off+k+1]) {
+				x = vb[off+k+1]
+			} else {
+				x = vb[off+k-1] + 1
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			vb[off+k] = x
+			if kf := delta - k; !odd && kf >= -step && kf <= step && vf[off+kf] >= n-x {
+				return a0 + n - x, b0 + m - y, a0 + n - sx, b0 + m - sy, true
+			}
+		}
+	}
+	panic("diff: middle snake not found")
+}
+
+func unifiedDiff(oldName, newName string, ops []diffOp) string {
+	var sb strings.Builder
+	aLine, bLine := 1, 1
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			aLine++
+			bLine++
+			i++
+			continue
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContext {
+				end += min(diffContext, run-end)
+				break
+			}
+			end = run
+		}
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		aStart, bStart := aLine-(i-start), bLine-(i-start)
+		var aCount, bCount int
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, op := range ops[start:end] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		for _, op := range ops[i:end] {
+			if op.kind != '+' {
+				aLine++
+			}
+			if op.kind != '-' {
+				bLine++
+			}
+		}
+		i = end
+	}
+	return sb.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
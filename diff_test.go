@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// applyUnifiedDiff applies a diff produced by unifiedDiff to a, requiring
+// every hunk to match exactly, without fuzz or offsets.
+func applyUnifiedDiff(a []string, diff string) ([]string, error) {
+	if diff == "" {
+		return a, nil
+	}
+	type record struct {
+		kind byte
+		text string
+	}
+	type hunk struct {
+		aStart, aCount, bStart, bCount int
+		lines                          []record
+	}
+	var hunks []*hunk
+	lines := strings.SplitAfter(diff, "\n")
+	if !strings.HasPrefix(lines[0], "--- ") || !strings.HasPrefix(lines[1], "+++ ") {
+		return nil, fmt.Errorf("missing file header")
+	}
+	for _, l := range lines[2:] {
+		switch {
+		case l == "":
+		case strings.HasPrefix(l, "@@ "):
+			h := &hunk{}
+			fields := strings.Fields(l)
+			var err error
+			if h.aStart, h.aCount, err = parseHunkRange(strings.TrimPrefix(fields[1], "-")); err != nil {
+				return nil, err
+			}
+			if h.bStart, h.bCount, err = parseHunkRange(strings.TrimPrefix(fields[2], "+")); err != nil {
+				return nil, err
+			}
+			hunks = append(hunks, h)
+		case strings.HasPrefix(l, `\ No newline at end of file`):
+			h := hunks[len(hunks)-1]
+			last := &h.lines[len(h.lines)-1]
+			last.text = strings.TrimSuffix(last.text, "\n")
+		default:
+			if len(hunks) == 0 {
+				return nil, fmt.Errorf("line outside hunk: %q", l)
+			}
+			h := hunks[len(hunks)-1]
+			h.lines = append(h.lines, record{l[0], l[1:]})
+		}
+	}
+
+	var out []string
+	pos := 0
+	for _, h := range hunks {
+		start := h.aStart - 1
+		if h.aCount == 0 {
+			start = h.aStart
+		}
+		if start < pos || start > len(a) {
+			return nil, fmt.Errorf("hunk at %d out of order", h.aStart)
+		}
+		out = append(out, a[pos:start]...)
+		pos = start
+		if want := h.bStart - 1; h.bCount > 0 && len(out) != want {
+			return nil, fmt.Errorf("hunk +%d starts at output line %d", h.bStart, len(out)+1)
+		}
+		var aCount, bCount int
+		for _, r := range h.lines {
+			switch r.kind {
+			case ' ', '-':
+				if pos >= len(a) || a[pos] != r.text {
+					return nil, fmt.Errorf("hunk -%d does not match at line %d", h.aStart, pos+1)
+				}
+				pos++
+				aCount++
+				if r.kind == ' ' {
+					out = append(out, r.text)
+					bCount++
+				}
+			case '+':
+				out = append(out, r.text)
+				bCount++
+			default:
+				return nil, fmt.Errorf("bad line kind %q", r.kind)
+			}
+		}
+		if aCount != h.aCount || bCount != h.bCount {
+			return nil, fmt.Errorf("hunk -%d,%d +%d,%d has %d/%d lines", h.aStart, h.aCount, h.bStart, h.bCount, aCount, bCount)
+		}
+	}
+	return append(out, a[pos:]...), nil
+}
+
+func parseHunkRange(s string) (start, count int, err error) {
+	startStr, countStr, ok := strings.Cut(s, ",")
+	if start, err = strconv.Atoi(startStr); err != nil {
+		return 0, 0, err
+	}
+	if !ok {
+		return start, 1, nil
+	}
+	count, err = strconv.Atoi(countStr)
+	return start, count, err
+}
+
+func lcsLength(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+	return dp[0][0]
+}
+
+func checkDiffRoundTrip(t *testing.T, a, b []string) {
+	t.Helper()
+	ops := diffLines(a, b)
+	var fromOps, toOps []string
+	edits := 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			fromOps = append(fromOps, op.line)
+		}
+		if op.kind != '-' {
+			toOps = append(toOps, op.line)
+		}
+		if op.kind != ' ' {
+			edits++
+		}
+	}
+	if strings.Join(fromOps, "") != strings.Join(a, "") || strings.Join(toOps, "") != strings.Join(b, "") {
+		t.Fatalf("edit script does not reproduce its inputs\na=%q\nb=%q", a, b)
+	}
+	if want := len(a) + len(b) - 2*lcsLength(a, b); edits != want {
+		t.Errorf("edit script has %d edits, shortest has %d\na=%q\nb=%q", edits, want, a, b)
+	}
+
+	diff := unifiedDiff("a/file", "b/file", ops)
+	got, err := applyUnifiedDiff(a, diff)
+	if err != nil {
+		t.Fatalf("applying diff: %v\na=%q\nb=%q\n%s", err, a, b, diff)
+	}
+	if strings.Join(got, "") != strings.Join(b, "") {
+		t.Fatalf("patched result differs\na=%q\nb=%q\ngot=%q\n%s", a, b, got, diff)
+	}
+}
+
+func TestDiffRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"identical", "a\nb\nc\n", "a\nb\nc\n"},
+		{"empty to content", "", "a\nb\n"},
+		{"content to empty", "a\nb\n", ""},
+		{"replace middle", "a\nb\nc\nd\ne\n", "a\nb\nX\nd\ne\n"},
+		{"distant changes", "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n", "X\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\nY\n"},
+		{"close changes merge", "1\n2\n3\n4\n5\n6\n7\n8\n", "X\n2\n3\n4\n5\n6\n7\nY\n"},
+		{"add missing newline", "a\nb", "a\nb\n"},
+		{"drop final newline", "a\nb\n", "a\nb"},
+		{"insert at start", "b\nc\n", "a\nb\nc\n"},
+		{"append", "a\n", "a\nb\nc\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkDiffRoundTrip(t, splitLines([]byte(tt.a)), splitLines([]byte(tt.b)))
+		})
+	}
+}
+
+func TestDiffRoundTripRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	alphabet := []string{"a\n", "b\n", "c\n", "d\n", "}\n", "\n"}
+	random := func() []string {
+		lines := make([]string, r.Intn(40))
+		for i := range lines {
+			lines[i] = alphabet[r.Intn(len(alphabet))]
+		}
+		return lines
+	}
+	for i := 0; i < 500; i++ {
+		a := random()
+		b := append([]string(nil), a...)
+		for j := r.Intn(6); j > 0 && len(b) > 0; j-- {
+			k := r.Intn(len(b))
+			switch r.Intn(3) {
+			case 0:
+				b = append(b[:k], b[k+1:]...)
+			case 1:
+				b = append(b[:k], append([]string{alphabet[r.Intn(len(alphabet))]}, b[k:]...)...)
+			default:
+				b[k] = alphabet[r.Intn(len(alphabet))]
+			}
+		}
+		if r.Intn(4) == 0 {
+			b = random()
+		}
+		checkDiffRoundTrip(t, a, b)
+	}
+}
+
+func TestDiffLinesFallback(t *testing.T) {
+	var a, b []string
+	for i := 0; i < maxDiffEdits+10; i++ {
+		a = append(a, fmt.Sprintf("a%d\n", i))
+		b = append(b, fmt.Sprintf("b%d\n", i))
+	}
+	ops := diffLines(a, b)
+	if len(ops) != len(a)+len(b) || ops[0].kind != '-' || ops[len(ops)-1].kind != '+' {
+		t.Fatalf("expected whole-file replacement, got %d ops", len(ops))
+	}
+	got, err := applyUnifiedDiff(a, unifiedDiff("a", "b", ops))
+	if err != nil || strings.Join(got, "") != strings.Join(b, "") {
+		t.Fatalf("fallback diff does not apply: %v", err)
+	}
+}
+
+func TestDiffLinesLarge(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 50000; i++ {
+		a = append(a, fmt.Sprintf("line %d\n", i))
+		if i%50 == 7 {
+			b = append(b, fmt.Sprintf("changed %d\n", i))
+		} else {
+			b = append(b, a[i])
+		}
+	}
+	ops := diffLines(a, b)
+	edits := 0
+	for _, op := range ops {
+		if op.kind != ' ' {
+			edits++
+		}
+	}
+	if want := 2 * 1000; edits != want {
+		t.Errorf("edits = %d, want %d", edits, want)
+	}
+	got, err := applyUnifiedDiff(a, unifiedDiff("a", "b", ops))
+	if err != nil || strings.Join(got, "") != strings.Join(b, "") {
+		t.Fatalf("diff does not apply: %v", err)
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const defaultFleetIndex = "fleet-index.json"
+
+type FleetRequirement struct {
+	Path     string `json:"Path"`
+	Version  string `json:"Version"`
+	Indirect bool   `json:"Indirect,omitempty"`
+}
+
+type FleetRepo struct {
+	URL       string             `json:"URL"`
+	Module    string             `json:"Module"`
+	GoVersion string             `json:"GoVersion,omitempty"`
+	Requires  []FleetRequirement `json:"Requires"`
+	Sums      []FleetSum         `json:"Sums,omitempty"`
+}
+
+type FleetSum struct {
+	Path    string `json:"Path"`
+	Version string `json:"Version"`
+	Hash    string `json:"Hash"`
+}
+
+type FleetIndex struct {
+	Repos []FleetRepo `json:"Repos"`
+}
+
+type Dependent struct {
+	Repo     string
+	Module   string
+	Version  string
+	Indirect bool
+}
+
+func readRequirements(goModPath string) (modFile *modfile.File, err error) {
+	sp := startSpan("parse go.mod", "gomod.path", goModPath)
+	defer func() { sp.End(err) }()
+	data, err := os.ReadFile(goModPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading go.mod: %v", err)
+	}
+	modFile, err = modfile.Parse(goModPath, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing go.mod: %v", err)
+	}
+	if modFile.Module == nil {
+		return nil, fmt.Errorf("go.mod has no module directive")
+	}
+	return modFile, nil
+}
+
+func analyzeFleetRepo(url string) (repo FleetRepo, err error) {
+	repo = FleetRepo{URL: url}
+	sp := startSpan("analyze repo", "repo.url", url)
+	defer func() {
+		sp.SetAttributes("module.path", repo.Module)
+		sp.End(err)
+	}()
+	dir, goModPath, err := checkoutRepo(url)
+	if dir != "" {
+		defer os.RemoveAll(dir)
+	}
+	if err != nil {
+		return repo, err
+	}
+	modFile, err := readRequirements(goModPath)
+	if err != nil {
+		return repo, err
+	}
+	repo.Module = modFile.Module.Mod.Path
+	if modFile.Go != nil {
+		repo.GoVersion = modFile.Go.Version
+	}
+	for _, r := range modFile.Require {
+		repo.Requires = append(repo.Requires, FleetRequirement{Path: r.Mod.Path, Version: r.Mod.Version, Indirect: r.Indirect})
+	}
+	repo.Sums, err = readGoSum(filepath.Join(filepath.Dir(goModPath), "go.sum"))
+	if err != nil && !os.IsNotExist(err) {
+		return repo, err
+	}
+	return repo, nil
+}
+
+func readGoSum(path string) ([]FleetSum, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var sums []FleetSum
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: malformed go.sum line", path, n)
+		}
+		sums = append(sums, FleetSum{Path: fields[0], Version: fields[1], Hash: fields[2]})
+	}
+	return sums, scanner.Err()
+}
+
+func buildFleetIndex(urls []string) (*FleetIndex, error) {
+	index := &FleetIndex{}
+	var failed []string
+	for _, url := range urls {
+		repo, err := analyzeFleetRepo(url)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping %s: %v\n", url, err)
+			failed = append(failed, url)
+			continue
+		}
+		index.Repos = append(index.Repos, repo)
+	}
+	if len(index.Repos) == 0 && len(failed) > 0 {
+		return nil, fmt.Errorf("no repositories could be analyzed")
+	}
+	return index, nil
+}
+
+func (idx *FleetIndex) Dependents(module string) []Dependent {
+	var deps []Dependent
+	for _, repo := range idx.Repos {
+		for _, r := range repo.Requires {
+			if r.Path == module {
+				deps = append(deps, Dependent{Repo: repo.URL, Module: repo.Module, Version: r.Version, Indirect: r.Indirect})
+			}
+		}
+	}
+	sort.Slice(deps, func(i, j int) bool { return deps[i].Module < deps[j].Module })
+	return deps
+}
+
+func loadFleetIndex(path string) (*FleetIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var index FleetIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	return &index, nil
+}
+
+func saveFleetIndex(path string, index *FleetIndex) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func readRepoList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var urls []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			urls = append(urls, line)
+		}
+	}
+	return urls, scanner.Err()
+}
+
+func latestVersion(module string) (version string, err error) {
+	sp := startSpan("proxy lookup", "module.path", module, "module.query", "latest")
+	defer func() {
+		sp.SetAttributes("module.version", version)
+		sp.End(err)
+	}()
+	out, err := runGo(os.TempDir(), "list", "-m", "-json", module+"@latest")
+	if err != nil {
+		return "", err
+	}
+	var m ModuleInfo
+	if err := json.Unmarshal(out, &m); err != nil {
+		return "", err
+	}
+	return m.Version, nil
+}
+
+func fleetRepoArgs(fs *flag.FlagSet, reposFile string) ([]string, error) {
+	urls := fs.Args()
+	if reposFile != "" {
+		list, err := readRepoList(reposFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading repository list: %v", err)
+		}
+		urls = append(urls, list...)
+	}
+	return urls, nil
+}
+
+func indexCommand(fs *flag.FlagSet) func() error {
+	output := fs.String("o", defaultFleetIndex, "file to write the reverse-dependency index to")
+	reposFile := fs.String("repos", "", "file listing repository URLs, one per line")
+	return func() error { return runIndex(fs, *output, *reposFile) }
+}
+
+func runIndex(fs *flag.FlagSet, output, reposFile string) error {
+	urls, err := fleetRepoArgs(fs, reposFile)
+	if err != nil {
+		return err
+	}
+	if len(urls) == 0 {
+		return errUsage
+	}
+
+	index, err := buildFleetIndex(urls)
+	if err != nil {
+		return fmt.Errorf("error building index: %v", err)
+	}
+	if err := saveFleetIndex(output, index); err != nil {
+		return fmt.Errorf("error writing index: %v", err)
+	}
+	fmt.Printf("Indexed %d repositories into %s\n", len(index.Repos), output)
+	return nil
+}
+
+func dependentsCommand(fs *flag.FlagSet) func() error {
+	indexPath := fs.String("index", defaultFleetIndex, "reverse-dependency index built by the index command")
+	latest := fs.String("latest", "", "release to compare against (default: the module's @latest version)")
+	return func() error { return runDependents(fs, *indexPath, *latest) }
+}
+
+func runDependents(fs *flag.FlagSet, indexPath, latest string) error {
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+	module := fs.Arg(0)
+
+	index, err := loadFleetIndex(indexPath)
+	if err != nil {
+		return fmt.Errorf("error loading index: %v", err)
+	}
+	target := latest
+	if target == "" {
+		if target, err = latestVersion(module); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: could not resolve latest release of %s: %v\n", module, err)
+		}
+	}
+
+	deps := index.Dependents(module)
+	if len(deps) == 0 {
+		fmt.Printf("No indexed repository depends on %s.\n", module)
+		return nil
+	}
+	if target != "" {
+		fmt.Printf("Dependents of %s (latest %s):\n", module, target)
+	} else {
+		fmt.Printf("Dependents of %s:\n", module)
+	}
+	for _, d := range deps {
+		kind := "direct"
+		if d.Indirect {
+			kind = "indirect"
+		}
+		status := ""
+		if target != "" && semver.Compare(d.Version, target) < 0 {
+			status = " needs bump to " + target
+		}
+		fmt.Printf("- %s (%s): %s %s%s\n", d.Module, d.Repo, d.Version, kind, status)
+	}
+	return nil
+}
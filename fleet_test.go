@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newGitRepo commits files into a fresh repository and returns its path,
+// which cloneRepo accepts like any other URL.
+func newGitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, files)
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadGoSum(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.sum":  "example.com/a v1.0.0 h1:abc=\nexample.com/a v1.0.0/go.mod h1:def=\n\n",
+		"bad.sum": "example.com/a v1.0.0\n",
+	})
+	sums, err := readGoSum(filepath.Join(dir, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FleetSum{
+		{Path: "example.com/a", Version: "v1.0.0", Hash: "h1:abc="},
+		{Path: "example.com/a", Version: "v1.0.0/go.mod", Hash: "h1:def="},
+	}
+	if !reflect.DeepEqual(sums, want) {
+		t.Errorf("sums = %+v, want %+v", sums, want)
+	}
+	if _, err := readGoSum(filepath.Join(dir, "bad.sum")); err == nil {
+		t.Error("malformed go.sum parsed without error")
+	}
+}
+
+func TestFleetIndexDependents(t *testing.T) {
+	index := &FleetIndex{Repos: []FleetRepo{
+		{URL: "u/z", Module: "example.com/z", Requires: []FleetRequirement{{Path: "example.com/lib", Version: "v1.2.0"}}},
+		{URL: "u/a", Module: "example.com/a", Requires: []FleetRequirement{
+			{Path: "example.com/other", Version: "v0.1.0"},
+			{Path: "example.com/lib", Version: "v1.1.0", Indirect: true},
+		}},
+		{URL: "u/b", Module: "example.com/b"},
+	}}
+	want := []Dependent{
+		{Repo: "u/a", Module: "example.com/a", Version: "v1.1.0", Indirect: true},
+		{Repo: "u/z", Module: "example.com/z", Version: "v1.2.0"},
+	}
+	if got := index.Dependents("example.com/lib"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependents = %+v, want %+v", got, want)
+	}
+	if got := index.Dependents("example.com/none"); got != nil {
+		t.Errorf("Dependents of unused module = %+v", got)
+	}
+}
+
+func TestBuildFleetIndex(t *testing.T) {
+	repo := newGitRepo(t, map[string]string{
+		"go.mod": "module example.com/svc\n\ngo 1.21\n\nrequire (\n\texample.com/lib v1.1.0\n\texample.com/util v0.2.0 // indirect\n)\n",
+		"go.sum": "example.com/lib v1.1.0 h1:abc=\n",
+	})
+	saved := globals.quiet
+	globals.quiet = true
+	defer func() { globals.quiet = saved }()
+
+	index, err := buildFleetIndex([]string{repo, filepath.Join(t.TempDir(), "missing")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Repos) != 1 {
+		t.Fatalf("indexed %d repos, want 1 (the missing one is skipped)", len(index.Repos))
+	}
+	want := FleetRepo{
+		URL:       repo,
+		Module:    "example.com/svc",
+		GoVersion: "1.21",
+		Requires: []FleetRequirement{
+			{Path: "example.com/lib", Version: "v1.1.0"},
+			{Path: "example.com/util", Version: "v0.2.0", Indirect: true},
+		},
+		Sums: []FleetSum{{Path: "example.com/lib", Version: "v1.1.0", Hash: "h1:abc="}},
+	}
+	if !reflect.DeepEqual(index.Repos[0], want) {
+		t.Errorf("repo = %+v, want %+v", index.Repos[0], want)
+	}
+
+	path := filepath.Join(t.TempDir(), "index.json")
+	if err := saveFleetIndex(path, index); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadFleetIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, index) {
+		t.Errorf("loaded index = %+v, want %+v", loaded, index)
+	}
+
+	if _, err := buildFleetIndex([]string{filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("index of only unreachable repositories built without error")
+	}
+}
+
+func TestReadRepoList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repos.txt")
+	writeFiles(t, filepath.Dir(path), map[string]string{
+		"repos.txt": "# services\nhttps://example.com/a.git\n\n  https://example.com/b.git  \n",
+	})
+	urls, err := readRepoList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"https://example.com/a.git", "https://example.com/b.git"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("urls = %v, want %v", urls, want)
+	}
+}
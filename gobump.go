@@ -0,0 +1,349 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"go/version"
+	"golang.org/x/mod/modfile"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type LoopVarSite struct {
+	File     string `json:"File"`
+	Line     int    `json:"Line"`
+	LoopLine int    `json:"LoopLine"`
+	Variable string `json:"Variable"`
+	Use      string `json:"Use"`
+}
+
+type GodebugChange struct {
+	Version  string `json:"Version"`
+	Setting  string `json:"Setting"`
+	Package  string `json:"Package"`
+	Summary  string `json:"Summary"`
+	Imported bool   `json:"Imported"`
+	PinnedBy string `json:"PinnedBy,omitempty"`
+}
+
+type LanguageFeature struct {
+	Version string `json:"Version"`
+	Summary string `json:"Summary"`
+}
+
+type GoBumpImpact struct {
+	From     string            `json:"From"`
+	To       string            `json:"To"`
+	LoopVars []LoopVarSite     `json:"LoopVars"`
+	Godebug  []GodebugChange   `json:"Godebug"`
+	Features []LanguageFeature `json:"Features"`
+}
+
+// godebugHistory lists the GODEBUG settings whose default changed with the
+// go directive of the main module. Setting is the new default.
+var godebugHistory = []GodebugChange{
+	{Version: "1.21", Setting: "panicnil=0", Package: "runtime", Summary: "panic(nil) panics with *runtime.PanicNilError and recover no longer returns nil"},
+	{Version: "1.22", Setting: "httplaxcontentlength=0", Package: "net/http", Summary: "empty Content-Length headers are rejected"},
+	{Version: "1.22", Setting: "httpmuxgo121=0", Package: "net/http", Summary: "ServeMux patterns accept methods and wildcards and match with the new precedence rules"},
+	{Version: "1.22", Setting: "tls10server=0", Package: "crypto/tls", Summary: "servers default to TLS 1.2 as the minimum version"},
+	{Version: "1.22", Setting: "tlsrsakex=0", Package: "crypto/tls", Summary: "RSA key exchange cipher suites are no longer offered by default"},
+	{Version: "1.22", Setting: "tlsunsafeekm=0", Package: "crypto/tls", Summary: "ExportKeyingMaterial fails for TLS 1.2 connections without extended master secret"},
+	{Version: "1.23", Setting: "asynctimerchan=0", Package: "time", Summary: "timer channels are unbuffered and Stop/Reset drop stale values"},
+	{Version: "1.23", Setting: "gotypesalias=1", Package: "go/types", Summary: "type aliases are represented as *types.Alias"},
+	{Version: "1.23", Setting: "httpservecontentkeepheaders=0", Package: "net/http", Summary: "ServeContent drops caching and encoding headers when serving errors"},
+	{Version: "1.23", Setting: "tls3des=0", Package: "crypto/tls", Summary: "3DES cipher suites are no longer offered by default"},
+	{Version: "1.23", Setting: "x509keypairleaf=1", Package: "crypto/tls", Summary: "X509KeyPair populates Certificate.Leaf"},
+	{Version: "1.23", Setting: "x509negativeserial=0", Package: "crypto/x509", Summary: "certificates with negative serial numbers are rejected"},
+	{Version: "1.23", Setting: "winsymlink=1", Package: "os", Summary: "Windows mount points are no longer reported as symlinks"},
+	{Version: "1.23", Setting: "winreadlinkvolume=1", Package: "os", Summary: "Readlink on Windows returns volume-relative paths"},
+	{Version: "1.24", Setting: "randseednop=1", Package: "math/rand", Summary: "the deprecated Seed function is a no-op"},
+	{Version: "1.24", Setting: "x509usepolicies=1", Package: "crypto/x509", Summary: "Certificate.Policies is used instead of PolicyIdentifiers"},
+	{Version: "1.24", Setting: "x509rsacrt=0", Package: "crypto/x509", Summary: "RSA private key CRT values are recomputed instead of trusted"},
+	{Version: "1.24", Setting: "gotestjsonbuildtext=0", Package: "testing", Summary: "go test -json reports build output as JSON events"},
+	{Version: "1.25", Setting: "decoratemappings=1", Package: "runtime", Summary: "anonymous memory mappings are annotated on Linux"},
+	{Version: "1.25", Setting: "containermaxprocs=1", Package: "runtime", Summary: "GOMAXPROCS honors cgroup CPU limits"},
+	{Version: "1.25", Setting: "updatemaxprocs=1", Package: "runtime", Summary: "GOMAXPROCS follows changes to the available CPUs"},
+	{Version: "1.25", Setting: "tlssha1=0", Package: "crypto/tls", Summary: "SHA-1 signatures are rejected in TLS 1.2 handshakes"},
+	{Version: "1.25", Setting: "x509sha256skid=1", Package: "crypto/x509", Summary: "generated subject key identifiers use SHA-256"},
+}
+
+// languageFeatures lists the language changes gated by the go directive.
+var languageFeatures = []LanguageFeature{
+	{Version: "1.13", Summary: "binary and octal literals, digit separators and signed shift counts"},
+	{Version: "1.17", Summary: "conversions from slices to array pointers"},
+	{Version: "1.18", Summary: "generics: type parameters, constraints and the any identifier"},
+	{Version: "1.20", Summary: "conversions from slices to arrays; comparable satisfied by interfaces"},
+	{Version: "1.21", Summary: "min, max and clear builtins and more powerful type inference"},
+	{Version: "1.22", Summary: "range over integers and per-iteration loop variables"},
+	{Version: "1.23", Summary: "range over iterator functions"},
+	{Version: "1.24", Summary: "generic type aliases"},
+	{Version: "1.26", Summary: "new with an initial value expression and self-referential type constraints"},
+}
+
+func goBumpCommand(fs *flag.FlagSet) func() error {
+	format := fs.String("format", "text", "output format: text or json")
+	return func() error { return runGoBump(fs, *format) }
+}
+
+func runGoBump(fs *flag.FlagSet, format string) error {
+	if fs.NArg() != 2 || (format != "text" && format != "json") {
+		return errUsage
+	}
+	target := strings.TrimPrefix(fs.Arg(1), "go")
+	if !version.IsValid("go" + target) {
+		return fmt.Errorf("invalid go version %q", fs.Arg(1))
+	}
+	dir, goModPath, err := checkoutRepo(fs.Arg(0))
+	if dir != "" {
+		defer os.RemoveAll(dir)
+	}
+	if err != nil {
+		return fmt.Errorf("error preparing repository: %v", err)
+	}
+	impact, err := analyzeGoBump(goModPath, target)
+	if err != nil {
+		return fmt.Errorf("error analyzing go directive change: %v", err)
+	}
+	if format == "json" {
+		return printJSON(impact)
+	}
+	printGoBumpImpact(impact)
+	return nil
+}
+
+// analyzeGoBump reports what changes when the go directive of goModPath is
+// raised to target: loop variables that become per-iteration, GODEBUG
+// defaults that flip for main packages and tests, and language features
+// that become available.
+func analyzeGoBump(goModPath, target string) (*GoBumpImpact, error) {
+	data, err := os.ReadFile(goModPath)
+	if err != nil {
+		return nil, err
+	}
+	modFile, err := modfile.Parse(goModPath, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	current := "1.16"
+	if modFile.Go != nil {
+		current = modFile.Go.Version
+	}
+	if version.Compare("go"+target, "go"+current) <= 0 {
+		return nil, fmt.Errorf("go directive %s is not older than %s", current, target)
+	}
+	impact := &GoBumpImpact{From: current, To: target}
+	raised := func(v string) bool {
+		return version.Compare("go"+v, "go"+current) > 0 && version.Compare("go"+v, "go"+target) <= 0
+	}
+
+	sp := startSpan("go directive impact", "go.from", current, "go.to", target)
+	root := filepath.Dir(goModPath)
+	imports := make(map[string]bool)
+	directives := make(map[string]string)
+	checkLoops := raised("1.22")
+	fset := token.NewFileSet()
+	err = walkModuleGoFiles(root, func(path string) error {
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		for _, imp := range file.Imports {
+			if p, err := strconv.Unquote(imp.Path.Value); err == nil {
+				imports[p] = true
+			}
+		}
+		for _, group := range file.Comments {
+			for _, c := range group.List {
+				if setting, ok := strings.CutPrefix(c.Text, "//go:debug "); ok {
+					key, _, _ := strings.Cut(strings.TrimSpace(setting), "=")
+					directives[key] = fmt.Sprintf("//go:debug in %s", rel)
+				}
+			}
+		}
+		if checkLoops && version.Compare(fileGoVersion(file, current), "go1.22") < 0 {
+			impact.LoopVars = append(impact.LoopVars, loopVarSites(fset, file, rel)...)
+		}
+		return nil
+	})
+	sp.End(err)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range modFile.Godebug {
+		directives[g.Key] = fmt.Sprintf("godebug %s=%s in go.mod", g.Key, g.Value)
+	}
+	for _, change := range godebugHistory {
+		if !raised(change.Version) {
+			continue
+		}
+		key, _, _ := strings.Cut(change.Setting, "=")
+		change.Imported = imports[change.Package]
+		if by, ok := directives[key]; ok {
+			change.PinnedBy = by
+		} else if by, ok := directives["default"]; ok {
+			change.PinnedBy = by
+		}
+		impact.Godebug = append(impact.Godebug, change)
+	}
+	for _, f := range languageFeatures {
+		if raised(f.Version) {
+			impact.Features = append(impact.Features, f)
+		}
+	}
+	return impact, nil
+}
+
+// fileGoVersion returns the language version a file is compiled with: a
+// //go:build constraint can raise it above the go directive.
+func fileGoVersion(file *ast.File, goVersion string) string {
+	v := "go" + goVersion
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, c := range group.List {
+			if expr, err := constraint.Parse(c.Text); err == nil && constraint.IsGoBuild(c.Text) {
+				if fv := constraint.GoVersion(expr); fv != "" && version.Compare(fv, v) > 0 {
+					v = fv
+				}
+			}
+		}
+	}
+	return v
+}
+
+// loopVarSites finds the loop variables whose behavior changes once every
+// iteration gets its own copy: those captured by a function literal and
+// those whose address is taken. Implicit address-taking through pointer
+// method calls is not visible without type information.
+func loopVarSites(fset *token.FileSet, file *ast.File, name string) []LoopVarSite {
+	var sites []LoopVarSite
+	ast.Inspect(file, func(n ast.Node) bool {
+		var vars []*ast.Ident
+		var body *ast.BlockStmt
+		switch loop := n.(type) {
+		case *ast.RangeStmt:
+			if loop.Tok != token.DEFINE {
+				return true
+			}
+			for _, e := range []ast.Expr{loop.Key, loop.Value} {
+				if id, ok := e.(*ast.Ident); ok && id.Name != "_" {
+					vars = append(vars, id)
+				}
+			}
+			body = loop.Body
+		case *ast.ForStmt:
+			init, ok := loop.Init.(*ast.AssignStmt)
+			if !ok || init.Tok != token.DEFINE {
+				return true
+			}
+			for _, e := range init.Lhs {
+				if id, ok := e.(*ast.Ident); ok && id.Name != "_" {
+					vars = append(vars, id)
+				}
+			}
+			body = loop.Body
+		default:
+			return true
+		}
+		loopLine := fset.Position(n.Pos()).Line
+		for _, v := range vars {
+			if v.Obj == nil {
+				continue
+			}
+			if use, pos := loopVarUse(body, v.Obj); use != "" {
+				sites = append(sites, LoopVarSite{File: name, Line: fset.Position(pos).Line, LoopLine: loopLine, Variable: v.Name, Use: use})
+			}
+		}
+		return true
+	})
+	return sites
+}
+
+// loopVarUse returns the first use of obj in body that outlives or aliases
+// a single iteration.
+func loopVarUse(body *ast.BlockStmt, obj *ast.Object) (string, token.Pos) {
+	var use string
+	var pos token.Pos
+	var visit func(n ast.Node, inClosure bool) bool
+	visit = func(n ast.Node, inClosure bool) bool {
+		if use != "" {
+			return false
+		}
+		switch x := n.(type) {
+		case *ast.FuncLit:
+			ast.Inspect(x.Body, func(m ast.Node) bool { return visit(m, true) })
+			return false
+		case *ast.Ident:
+			if x.Obj == obj && inClosure {
+				use, pos = "captured by a closure", x.Pos()
+			}
+		case *ast.UnaryExpr:
+			if x.Op != token.AND {
+				return true
+			}
+			operand := x.X
+			for {
+				if s, ok := operand.(*ast.SelectorExpr); ok {
+					operand = s.X
+				} else if p, ok := operand.(*ast.ParenExpr); ok {
+					operand = p.X
+				} else {
+					break
+				}
+			}
+			if id, ok := operand.(*ast.Ident); ok && id.Obj == obj {
+				use, pos = "address taken", x.Pos()
+				return false
+			}
+		}
+		return true
+	}
+	ast.Inspect(body, func(n ast.Node) bool { return visit(n, false) })
+	return use, pos
+}
+
+func printGoBumpImpact(impact *GoBumpImpact) {
+	fmt.Printf("Raising the go directive from %s to %s\n", impact.From, impact.To)
+	if version.Compare("go"+impact.From, "go1.22") < 0 && version.Compare("go"+impact.To, "go1.22") >= 0 {
+		if len(impact.LoopVars) == 0 {
+			fmt.Println("Loop variables become per-iteration in go 1.22; no closures or addresses capture them.")
+		} else {
+			fmt.Println("Loop variables become per-iteration in go 1.22; review these uses:")
+			for _, s := range impact.LoopVars {
+				fmt.Printf("- %s:%d: %s %s (loop at line %d)\n", s.File, s.Line, s.Variable, s.Use, s.LoopLine)
+			}
+		}
+	}
+	if len(impact.Godebug) > 0 {
+		fmt.Println("GODEBUG defaults that change for main packages and tests:")
+		for _, g := range impact.Godebug {
+			var notes []string
+			if g.Imported {
+				notes = append(notes, g.Package+" imported")
+			}
+			if g.PinnedBy != "" {
+				notes = append(notes, "pinned by "+g.PinnedBy)
+			}
+			note := ""
+			if len(notes) > 0 {
+				note = " [" + strings.Join(notes, ", ") + "]"
+			}
+			fmt.Printf("- go %s %s: %s%s\n", g.Version, g.Setting, g.Summary, note)
+		}
+	}
+	if len(impact.Features) > 0 {
+		fmt.Println("Language features newly allowed:")
+		for _, f := range impact.Features {
+			fmt.Printf("- go %s: %s\n", f.Version, f.Summary)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const loopVarSource = `package p
+
+type T struct{ f int }
+
+func f(items []T, done chan bool) []*int {
+	var ptrs []*int
+	for i, item := range items {
+		go func() {
+			_ = item
+		}()
+		_ = i
+	}
+	for i := 0; i < 3; i++ {
+		ptrs = append(ptrs, &i)
+	}
+	for _, item := range items {
+		ptrs = append(ptrs, &(item.f))
+	}
+	for k := range items {
+		func(k int) { _ = k }(k)
+	}
+	var j int
+	for j = range items {
+		defer func() { _ = j }()
+	}
+	for _, item := range items {
+		item := item
+		go func() { _ = item }()
+	}
+	for i := range 3 {
+		defer func() {
+			if done != nil {
+				_ = i
+				_ = &i
+			}
+		}()
+	}
+	return ptrs
+}
+`
+
+func TestLoopVarSites(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", loopVarSource, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := loopVarSites(fset, file, "p.go")
+	want := []LoopVarSite{
+		{File: "p.go", Line: 9, LoopLine: 7, Variable: "item", Use: "captured by a closure"},
+		{File: "p.go", Line: 14, LoopLine: 13, Variable: "i", Use: "address taken"},
+		{File: "p.go", Line: 17, LoopLine: 16, Variable: "item", Use: "address taken"},
+		{File: "p.go", Line: 33, LoopLine: 30, Variable: "i", Use: "captured by a closure"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loopVarSites =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestFileGoVersion(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"package p\n", "go1.21"},
+		{"//go:build go1.22\n\npackage p\n", "go1.22"},
+		{"//go:build linux && go1.20\n\npackage p\n", "go1.21"},
+		{"// Package p is documented.\n\n//go:build go1.23\n\npackage p\n", "go1.23"},
+		{"package p\n\n//go:build go1.23\n", "go1.21"},
+	}
+	for _, tt := range tests {
+		file, err := parser.ParseFile(token.NewFileSet(), "p.go", tt.src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fileGoVersion(file, "1.21"); got != tt.want {
+			t.Errorf("fileGoVersion(%q) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeGoBump(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.21\n\ngodebug tlsrsakex=1\n",
+		"main.go": `//go:debug httpmuxgo121=1
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+var _ tls.Config
+var _ http.Handler
+
+func main() {
+	for _, v := range []int{1} {
+		go func() { _ = v }()
+	}
+}
+`,
+		"new.go": `//go:build go1.22
+
+package main
+
+func later() {
+	for _, v := range []int{1} {
+		go func() { _ = v }()
+	}
+}
+`,
+		"testdata/skip.go": "package skip\n\nfunc f() {\n\tfor i := 0; i < 1; i++ {\n\t\t_ = &i\n\t}\n}\n",
+		"nested/go.mod":    "module example.com/nested\n",
+		"nested/n.go":      "package n\n\nimport _ \"time\"\n",
+	})
+	goModPath := filepath.Join(dir, "go.mod")
+
+	impact, err := analyzeGoBump(goModPath, "1.23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []LoopVarSite{{File: "main.go", Line: 14, LoopLine: 13, Variable: "v", Use: "captured by a closure"}}; !reflect.DeepEqual(impact.LoopVars, want) {
+		t.Errorf("loop vars = %+v, want %+v", impact.LoopVars, want)
+	}
+
+	godebug := make(map[string]GodebugChange)
+	for _, g := range impact.Godebug {
+		if g.Version != "1.22" && g.Version != "1.23" {
+			t.Errorf("godebug change %s from go %s is outside (1.21, 1.23]", g.Setting, g.Version)
+		}
+		godebug[g.Setting] = g
+	}
+	if _, ok := godebug["asynctimerchan=0"]; !ok {
+		t.Errorf("godebug changes %v are missing asynctimerchan", impact.Godebug)
+	}
+	checks := []struct {
+		setting, pinnedBy string
+		imported          bool
+	}{
+		{"httpmuxgo121=0", "//go:debug in main.go", true},
+		{"tlsrsakex=0", "godebug tlsrsakex=1 in go.mod", true},
+		{"tls10server=0", "", true},
+		{"asynctimerchan=0", "", false},
+	}
+	for _, c := range checks {
+		g := godebug[c.setting]
+		if g.PinnedBy != c.pinnedBy || g.Imported != c.imported {
+			t.Errorf("%s: pinned by %q, imported %v; want %q, %v", c.setting, g.PinnedBy, g.Imported, c.pinnedBy, c.imported)
+		}
+	}
+
+	var features []string
+	for _, f := range impact.Features {
+		features = append(features, f.Version)
+	}
+	if want := []string{"1.22", "1.23"}; !equalStrings(features, want) {
+		t.Errorf("features = %v, want %v", features, want)
+	}
+
+	impact, err = analyzeGoBump(goModPath, "1.21.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(impact.LoopVars) != 0 || len(impact.Godebug) != 0 || len(impact.Features) != 0 {
+		t.Errorf("patch bump impact = %+v, want none", impact)
+	}
+	if _, err := analyzeGoBump(goModPath, "1.20"); err == nil {
+		t.Error("lowering the go directive succeeded")
+	}
+}
+
+func TestAnalyzeGoBumpDefaultPin(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.22\n\ngodebug default=go1.22\n",
+		"m.go":   "package m\n",
+	})
+	impact, err := analyzeGoBump(filepath.Join(dir, "go.mod"), "1.23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range impact.Godebug {
+		if g.PinnedBy != "godebug default=go1.22 in go.mod" {
+			t.Errorf("%s: pinned by %q, want the go.mod default", g.Setting, g.PinnedBy)
+		}
+	}
+	if len(impact.LoopVars) != 0 {
+		t.Errorf("loop vars checked although go 1.22 already applies: %+v", impact.LoopVars)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"go/version"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type GoVersionPin struct {
+	File    string
+	Line    int
+	Source  string
+	Version string
+}
+
+type GoVersionIssue struct {
+	GoVersionPin
+	Problem string
+}
+
+var (
+	dockerFromGolang  = regexp.MustCompile(`(?i)^\s*FROM\s+(?:--platform=\S+\s+)?(?:\S+/)?golang:(\S+)`)
+	dockerArg         = regexp.MustCompile(`(?i)^\s*ARG\s+(\w+)=(\S+)`)
+	dockerArgRef      = regexp.MustCompile(`\$\{?(\w+)\}?`)
+	workflowGoVersion = regexp.MustCompile(`^\s*-?\s*go-version:\s*(.+?)\s*(?:#.*)?$`)
+	goVersionNumber   = regexp.MustCompile(`^\d+\.\d+(?:\.\d+)?(?:(?:rc|beta)\d+)?`)
+)
+
+func findGoVersionPins(root string) ([]GoVersionPin, error) {
+	var pins []GoVersionPin
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := info.Name()
+		if info.IsDir() {
+			if path != root && (name == ".git" || name == "vendor" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		var found []GoVersionPin
+		lower := strings.ToLower(name)
+		switch {
+		case lower == "dockerfile" || strings.HasPrefix(lower, "dockerfile.") || strings.HasSuffix(lower, ".dockerfile"):
+			found, err = scanDockerfile(path)
+		case filepath.ToSlash(filepath.Dir(rel)) == ".github/workflows" && (strings.HasSuffix(lower, ".yml") || strings.HasSuffix(lower, ".yaml")):
+			found, err = scanWorkflow(path)
+		case name == ".tool-versions":
+			found, err = scanLines(path, ".tool-versions", func(line string) []string {
+				fields := strings.Fields(line)
+				if len(fields) >= 2 && fields[0] == "golang" {
+					return fields[1:2]
+				}
+				return nil
+			})
+		case name == ".go-version":
+			found, err = scanLines(path, ".go-version", func(line string) []string {
+				if line = strings.TrimSpace(line); line != "" {
+					return []string{line}
+				}
+				return nil
+			})
+		}
+		if err != nil {
+			return err
+		}
+		for _, p := range found {
+			p.File = rel
+			pins = append(pins, p)
+		}
+		return nil
+	})
+	return pins, err
+}
+
+func scanLines(path, source string, match func(line string) []string) ([]GoVersionPin, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var pins []GoVersionPin
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		for _, v := range match(scanner.Text()) {
+			pins = append(pins, GoVersionPin{Line: n, Source: source, Version: v})
+		}
+	}
+	return pins, scanner.Err()
+}
+
+func scanDockerfile(path string) ([]GoVersionPin, error) {
+	args := make(map[string]string)
+	return scanLines(path, "Dockerfile", func(line string) []string {
+		if m := dockerArg.FindStringSubmatch(line); m != nil {
+			args[m[1]] = strings.Trim(m[2], `"'`)
+			return nil
+		}
+		m := dockerFromGolang.FindStringSubmatch(line)
+		if m == nil {
+			return nil
+		}
+		tag := dockerArgRef.ReplaceAllStringFunc(m[1], func(ref string) string {
+			return args[dockerArgRef.FindStringSubmatch(ref)[1]]
+		})
+		return []string{tag}
+	})
+}
+
+func scanWorkflow(path string) ([]GoVersionPin, error) {
+	return scanLines(path, "GitHub workflow", func(line string) []string {
+		m := workflowGoVersion.FindStringSubmatch(line)
+		if m == nil || strings.Contains(m[1], "${{") {
+			return nil
+		}
+		var versions []string
+		for _, v := range strings.Split(strings.Trim(m[1], "[]"), ",") {
+			if v = strings.Trim(strings.TrimSpace(v), `"'`); v != "" {
+				versions = append(versions, v)
+			}
+		}
+		return versions
+	})
+}
+
+func normalizeGoVersion(v string) string {
+	v = strings.TrimLeft(strings.TrimPrefix(strings.TrimPrefix(v, "go"), "v"), "^~>=")
+	return goVersionNumber.FindString(v)
+}
+
+func checkGoVersionPins(goVersion, toolchain string, pins []GoVersionPin) []GoVersionIssue {
+	if goVersion == "" {
+		return nil
+	}
+	required := "go" + goVersion
+	expected, expectedFrom := version.Lang(required), "go directive "+goVersion
+	if toolchain != "" && version.IsValid(toolchain) {
+		expected, expectedFrom = version.Lang(toolchain), "toolchain "+toolchain
+	}
+
+	var issues []GoVersionIssue
+	for _, pin := range pins {
+		v := normalizeGoVersion(pin.Version)
+		if v == "" {
+			issues = append(issues, GoVersionIssue{pin, "not pinned to a Go version"})
+			continue
+		}
+		pinned := "go" + v
+		floor := required
+		if pinned == version.Lang(pinned) {
+			floor = version.Lang(required)
+		}
+		switch {
+		case version.Compare(pinned, floor) < 0:
+			issues = append(issues, GoVersionIssue{pin, fmt.Sprintf("older than go directive %s", goVersion)})
+		case version.Lang(pinned) != expected:
+			issues = append(issues, GoVersionIssue{pin, fmt.Sprintf("differs from %s", expectedFrom)})
+		}
+	}
+	return issues
+}
+
+func printGoVersionIssues(toolchain string, issues []GoVersionIssue) {
+	if toolchain != "" {
+		fmt.Printf("Go Toolchain: %s\n", toolchain)
+	}
+	if len(issues) == 0 {
+		fmt.Println("Go versions in Dockerfiles, CI workflows and version files match go.mod.")
+		return
+	}
+	fmt.Println("Go version inconsistencies:")
+	for _, issue := range issues {
+		fmt.Printf("- %s:%d (%s): %s %s\n", issue.File, issue.Line, issue.Source, issue.Version, issue.Problem)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeGoVersion(t *testing.T) {
+	tests := map[string]string{
+		"1.22":            "1.22",
+		"1.22.3":          "1.22.3",
+		"go1.21.0":        "1.21.0",
+		"v1.20":           "1.20",
+		"^1.22":           "1.22",
+		">=1.21":          "1.21",
+		"1.23rc1":         "1.23rc1",
+		"1.22-alpine":     "1.22",
+		"1.22.1-bookworm": "1.22.1",
+		"stable":          "",
+		"latest":          "",
+	}
+	for in, want := range tests {
+		if got := normalizeGoVersion(in); got != want {
+			t.Errorf("normalizeGoVersion(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCheckGoVersionPins(t *testing.T) {
+	tests := []struct {
+		name      string
+		goVersion string
+		toolchain string
+		pin       string
+		wantIssue string
+	}{
+		{"same language version", "1.22.0", "", "1.22", ""},
+		{"patch at floor", "1.22.3", "", "1.22.3", ""},
+		{"patch below floor", "1.22.3", "", "1.22.1", "older than go directive 1.22.3"},
+		{"older language version", "1.22", "", "1.21", "older than go directive 1.22"},
+		{"newer language version", "1.21", "", "1.23", "differs from go directive 1.21"},
+		{"matches toolchain", "1.21", "go1.23.2", "1.23", ""},
+		{"differs from toolchain", "1.21", "go1.23.2", "1.22", "differs from toolchain go1.23.2"},
+		{"not a version", "1.22", "", "stable", "not pinned to a Go version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pin := GoVersionPin{File: "Dockerfile", Line: 1, Source: "Dockerfile", Version: tt.pin}
+			issues := checkGoVersionPins(tt.goVersion, tt.toolchain, []GoVersionPin{pin})
+			switch {
+			case tt.wantIssue == "" && len(issues) != 0:
+				t.Errorf("unexpected issue %q", issues[0].Problem)
+			case tt.wantIssue != "" && (len(issues) != 1 || issues[0].Problem != tt.wantIssue):
+				t.Errorf("issues = %+v, want %q", issues, tt.wantIssue)
+			}
+		})
+	}
+	if issues := checkGoVersionPins("", "", []GoVersionPin{{Version: "1.10"}}); issues != nil {
+		t.Errorf("without a go directive got %+v", issues)
+	}
+}
+
+func TestFindGoVersionPins(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"Dockerfile":               "ARG GO_VERSION=1.22.1\nFROM golang:${GO_VERSION}-alpine AS build\nFROM --platform=linux/amd64 docker.io/library/golang:1.21\n",
+		".github/workflows/ci.yml": "jobs:\n  test:\n    steps:\n      - uses: actions/setup-go@v5\n        with:\n          go-version: '1.22' # pinned\n      - go-version: [1.21, \"1.22\"]\n      - go-version: ${{ matrix.go }}\n",
+		".tool-versions":           "nodejs 20.1.0\ngolang 1.22.2\n",
+		"sub/.go-version":          "1.20\n",
+		"vendor/x/Dockerfile":      "FROM golang:1.10\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pins, err := findGoVersionPins(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]string)
+	for _, p := range pins {
+		got[filepath.ToSlash(p.File)] = append(got[filepath.ToSlash(p.File)], p.Version)
+	}
+	want := map[string][]string{
+		"Dockerfile":               {"1.22.1-alpine", "1.21"},
+		".github/workflows/ci.yml": {"1.22", "1.21", "1.22"},
+		".tool-versions":           {"1.22.2"},
+		"sub/.go-version":          {"1.20"},
+	}
+	if len(got) != len(want) {
+		t.Errorf("pins = %v, want %v", got, want)
+	}
+	for file, versions := range want {
+		if g := got[file]; len(g) != len(versions) || !equalStrings(g, versions) {
+			t.Errorf("%s: pins = %v, want %v", file, g, versions)
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func graphCommand(fs *flag.FlagSet) func() error {
+	format := fs.String("format", "text", "output format: text, dot or json")
+	metrics := fs.Bool("metrics", false, "print depth, fan-in/out, betweenness and critical modules instead of the edges")
+	top := fs.Int("top", defaultCriticalModules, "number of critical modules to report with -metrics")
+	return func() error { return runGraph(fs, *format, *metrics, *top) }
+}
+
+func runGraph(fs *flag.FlagSet, format string, metrics bool, top int) error {
+	if fs.NArg() != 1 || (format != "text" && format != "dot" && format != "json") {
+		return errUsage
+	}
+	dir, goModPath, err := checkoutRepo(fs.Arg(0))
+	if dir != "" {
+		defer os.RemoveAll(dir)
+	}
+	if err != nil {
+		return fmt.Errorf("error preparing repository: %v", err)
+	}
+	edges, err := getModuleGraph(filepath.Dir(goModPath))
+	if err != nil {
+		return fmt.Errorf("error reading module graph: %v", err)
+	}
+
+	if metrics {
+		mainModule, _, _, err := parseGoMod(goModPath)
+		if err != nil {
+			return fmt.Errorf("error parsing go.mod: %v", err)
+		}
+		modules, err := listModules(filepath.Dir(goModPath), false)
+		if err != nil {
+			return fmt.Errorf("error listing modules: %v", err)
+		}
+		result := computeGraphMetrics(edges, mainModule, modules, top)
+		if format == "json" {
+			return printJSON(result)
+		}
+		printGraphMetrics(result)
+		return nil
+	}
+
+	switch format {
+	case "text":
+		for _, e := range edges {
+			fmt.Printf("%s %s\n", e.From, e.To)
+		}
+	case "dot":
+		fmt.Println("digraph modules {")
+		for _, e := range edges {
+			fmt.Printf("\t%q -> %q;\n", e.From, e.To)
+		}
+		fmt.Println("}")
+	case "json":
+		return printJSON(edges)
+	}
+	return nil
+}
+
+func printJSON(v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding JSON: %v", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func whyCommand(fs *flag.FlagSet) func() error {
+	return func() error { return runWhy(fs) }
+}
+
+func runWhy(fs *flag.FlagSet) error {
+	if fs.NArg() != 2 {
+		return errUsage
+	}
+	dir, goModPath, err := checkoutRepo(fs.Arg(0))
+	if dir != "" {
+		defer os.RemoveAll(dir)
+	}
+	if err != nil {
+		return fmt.Errorf("error preparing repository: %v", err)
+	}
+	mainModule, _, _, err := parseGoMod(goModPath)
+	if err != nil {
+		return fmt.Errorf("error parsing go.mod: %v", err)
+	}
+	edges, err := getModuleGraph(filepath.Dir(goModPath))
+	if err != nil {
+		return fmt.Errorf("error reading module graph: %v", err)
+	}
+
+	target := fs.Arg(1)
+	fmt.Printf("# %s\n", target)
+	chain := requirementChain(edges, mainModule, target)
+	if chain == nil {
+		fmt.Printf("(main module does not need module %s)\n", target)
+		return nil
+	}
+	for _, m := range chain {
+		fmt.Println(m)
+	}
+	return nil
+}
+
+// requirementChain returns the shortest path through the requirement graph
+// from the main module to any version of target, or nil if target is not
+// reachable.
+func requirementChain(edges []GraphEdge, mainModule, target string) []string {
+	next := make(map[string][]string)
+	for _, e := range edges {
+		next[e.From] = append(next[e.From], e.To)
+	}
+	prev := map[string]string{mainModule: ""}
+	queue := []string{mainModule}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if path, _, _ := strings.Cut(cur, "@"); path == target {
+			var chain []string
+			for m := cur; m != ""; m = prev[m] {
+				chain = append([]string{m}, chain...)
+			}
+			return chain
+		}
+		for _, m := range next[cur] {
+			if _, seen := prev[m]; !seen {
+				prev[m] = cur
+				queue = append(queue, m)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRequirementChain(t *testing.T) {
+	edges := []GraphEdge{
+		{"example.com/m", "example.com/a@v1.0.0"},
+		{"example.com/m", "example.com/b@v1.0.0"},
+		{"example.com/a@v1.0.0", "example.com/c@v1.0.0"},
+		{"example.com/c@v1.0.0", "example.com/d@v1.0.0"},
+		{"example.com/b@v1.0.0", "example.com/d@v1.1.0"},
+		{"example.com/d@v1.1.0", "example.com/b@v1.0.0"},
+		{"example.com/x@v1.0.0", "example.com/y@v1.0.0"},
+	}
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{"example.com/a", []string{"example.com/m", "example.com/a@v1.0.0"}},
+		{"example.com/c", []string{"example.com/m", "example.com/a@v1.0.0", "example.com/c@v1.0.0"}},
+		// The shortest chain wins, whichever version it reaches.
+		{"example.com/d", []string{"example.com/m", "example.com/b@v1.0.0", "example.com/d@v1.1.0"}},
+		{"example.com/m", []string{"example.com/m"}},
+		{"example.com/y", nil},
+		{"example.com/missing", nil},
+		{"example.com/a@v1.0.0", nil},
+	}
+	for _, tt := range tests {
+		if got := requirementChain(edges, "example.com/m", tt.target); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("requirementChain(%s) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type legacyPin struct {
+	Path     string
+	Revision string
+	Version  string
+}
+
+type legacyManifest struct {
+	file  string
+	tool  string
+	parse func(dir string) (modulePath string, pins []legacyPin, err error)
+}
+
+var legacyManifests = []legacyManifest{
+	{"Gopkg.lock", "dep", parseGopkgLock},
+	{"glide.lock", "glide", parseGlideLock},
+	{filepath.Join("vendor", "vendor.json"), "govendor", parseVendorJSON},
+	{filepath.Join("Godeps", "Godeps.json"), "godep", parseGodepsJSON},
+}
+
+// migrateLegacy writes a go.mod for a repository still managed by dep,
+// glide, govendor or godep, translating the pinned revisions into module
+// requirements. The old vendor directory is moved aside so that the go
+// command does not insist on vendoring.
+func migrateLegacy(dir, repoURL string) (string, error) {
+	for _, m := range legacyManifests {
+		if _, err := os.Stat(filepath.Join(dir, m.file)); err != nil {
+			continue
+		}
+		sp := startSpan("migrate legacy manifest", "manifest", m.file)
+		goModPath, err := writeMigratedGoMod(dir, repoURL, m)
+		sp.End(err)
+		return goModPath, err
+	}
+	return "", fmt.Errorf("could not find go.mod or a dep, glide, govendor or godep manifest")
+}
+
+func writeMigratedGoMod(dir, repoURL string, m legacyManifest) (string, error) {
+	modulePath, pins, err := m.parse(dir)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %v", m.file, err)
+	}
+	if modulePath == "" {
+		modulePath = modulePathFromURL(repoURL)
+	}
+	goVersion, err := runGo(dir, "env", "GOVERSION")
+	if err != nil {
+		return "", err
+	}
+
+	f := &modfile.File{}
+	if err := f.AddModuleStmt(modulePath); err != nil {
+		return "", err
+	}
+	v := strings.TrimPrefix(strings.TrimSpace(string(goVersion)), "go")
+	if major, minor, ok := strings.Cut(v, "."); ok {
+		minor, _, _ = strings.Cut(minor, ".")
+		v = major + "." + minor
+	}
+	if err := f.AddGoStmt(v); err != nil {
+		return "", err
+	}
+
+	seen := make(map[string]bool)
+	var unresolved []string
+	for _, pin := range pins {
+		root := legacyRepoRoot(pin.Path)
+		if seen[root] || root == modulePath {
+			continue
+		}
+		seen[root] = true
+		resolved, version, err := resolveLegacyPin(root, pin)
+		if err != nil {
+			unresolved = append(unresolved, fmt.Sprintf("%s@%s: %v", root, pin.Revision, err))
+			continue
+		}
+		f.AddNewRequire(resolved, version, false)
+	}
+	f.SortBlocks()
+	f.Cleanup()
+	data, err := f.Format()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "vendor")); err == nil {
+		if err := os.Rename(filepath.Join(dir, "vendor"), filepath.Join(dir, "_vendor")); err != nil {
+			return "", err
+		}
+	}
+	goModPath := filepath.Join(dir, "go.mod")
+	if err := os.WriteFile(goModPath, data, 0o644); err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(os.Stderr, "note: no go.mod found; proposed go.mod migrated from %s (%s):\n%s", m.file, m.tool, data)
+	for _, u := range unresolved {
+		fmt.Fprintf(os.Stderr, "warning: could not resolve %s\n", u)
+	}
+	if _, err := runGo(dir, "mod", "download"); err != nil {
+		return "", fmt.Errorf("error downloading migrated requirements: %v", err)
+	}
+	return goModPath, nil
+}
+
+// resolveLegacyPin asks the module proxy for the pinned tag first and falls
+// back to the revision, which resolves to a pseudo-version.
+func resolveLegacyPin(path string, pin legacyPin) (string, string, error) {
+	var queries []string
+	if pin.Version != "" && semver.IsValid(pin.Version) {
+		queries = append(queries, pin.Version)
+	}
+	if pin.Revision != "" {
+		queries = append(queries, pin.Revision)
+	}
+	if len(queries) == 0 {
+		queries = append(queries, "latest")
+	}
+	var lastErr error
+	for _, q := range queries {
+		sp := startSpan("proxy lookup", "module.path", path, "module.query", q)
+		out, err := runGo(os.TempDir(), "list", "-m", "-json", path+"@"+q)
+		sp.End(err)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		var m ModuleInfo
+		if err := json.Unmarshal(out, &m); err != nil {
+			return "", "", err
+		}
+		return m.Path, m.Version, nil
+	}
+	return "", "", lastErr
+}
+
+// legacyRepoRoot maps a package path to the root of its repository for
+// the well-known hosts; other paths are left for the go command to resolve.
+func legacyRepoRoot(importPath string) string {
+	parts := strings.Split(importPath, "/")
+	switch {
+	case parts[0] == "github.com" || parts[0] == "gitlab.com" || parts[0] == "bitbucket.org" || strings.HasPrefix(importPath, "golang.org/x/"):
+		if len(parts) > 3 {
+			return strings.Join(parts[:3], "/")
+		}
+	case parts[0] == "gopkg.in":
+		for i, p := range parts {
+			if i > 0 && strings.Contains(p, ".v") {
+				return strings.Join(parts[:i+1], "/")
+			}
+		}
+	}
+	return importPath
+}
+
+func modulePathFromURL(repoURL string) string {
+	s := strings.TrimSuffix(strings.TrimSuffix(repoURL, "/"), ".git")
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		s = u.Host + u.Path
+	} else if host, path, ok := strings.Cut(strings.TrimPrefix(s, "git@"), ":"); ok && strings.HasPrefix(s, "git@") {
+		s = host + "/" + path
+	} else {
+		s = filepath.Base(s)
+	}
+	if err := module.CheckImportPath(s); err != nil {
+		return "example.com/" + filepath.Base(s)
+	}
+	return s
+}
+
+// parseGopkgLock reads the [[projects]] tables of a dep lock file.
+func parseGopkgLock(dir string) (string, []legacyPin, error) {
+	f, err := os.Open(filepath.Join(dir, "Gopkg.lock"))
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	var pins []legacyPin
+	inProject := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inProject = line == "[[projects]]"
+			if inProject {
+				pins = append(pins, legacyPin{})
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !inProject || !ok {
+			continue
+		}
+		cur := &pins[len(pins)-1]
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		switch strings.TrimSpace(key) {
+		case "name":
+			cur.Path = value
+		case "revision":
+			cur.Revision = value
+		case "version":
+			cur.Version = value
+		}
+	}
+	return "", pins, scanner.Err()
+}
+
+// parseGlideLock reads the imports and testImports lists of glide.lock and
+// takes the module path from the package field of glide.yaml.
+func parseGlideLock(dir string) (string, []legacyPin, error) {
+	f, err := os.Open(filepath.Join(dir, "glide.lock"))
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	var pins []legacyPin
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if name, ok := strings.CutPrefix(trimmed, "- name:"); ok {
+			pins = append(pins, legacyPin{Path: strings.TrimSpace(name)})
+		} else if rev, ok := strings.CutPrefix(trimmed, "version:"); ok && len(pins) > 0 && strings.HasPrefix(line, " ") {
+			pins[len(pins)-1].Revision = strings.TrimSpace(rev)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, err
+	}
+
+	var modulePath string
+	if data, err := os.ReadFile(filepath.Join(dir, "glide.yaml")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if pkg, ok := strings.CutPrefix(line, "package:"); ok {
+				modulePath = strings.Trim(strings.TrimSpace(pkg), `"'`)
+				break
+			}
+		}
+	}
+	return modulePath, pins, nil
+}
+
+func parseVendorJSON(dir string) (string, []legacyPin, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "vendor", "vendor.json"))
+	if err != nil {
+		return "", nil, err
+	}
+	var manifest struct {
+		RootPath string `json:"rootPath"`
+		Package  []struct {
+			Path         string `json:"path"`
+			Revision     string `json:"revision"`
+			VersionExact string `json:"versionExact"`
+		} `json:"package"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return "", nil, err
+	}
+	var pins []legacyPin
+	for _, p := range manifest.Package {
+		pins = append(pins, legacyPin{Path: p.Path, Revision: p.Revision, Version: p.VersionExact})
+	}
+	return manifest.RootPath, pins, nil
+}
+
+func parseGodepsJSON(dir string) (string, []legacyPin, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "Godeps", "Godeps.json"))
+	if err != nil {
+		return "", nil, err
+	}
+	var manifest struct {
+		ImportPath string `json:"ImportPath"`
+		Deps       []struct {
+			ImportPath string `json:"ImportPath"`
+			Comment    string `json:"Comment"`
+			Rev        string `json:"Rev"`
+		} `json:"Deps"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return "", nil, err
+	}
+	var pins []legacyPin
+	for _, d := range manifest.Deps {
+		pins = append(pins, legacyPin{Path: d.ImportPath, Revision: d.Rev, Version: d.Comment})
+	}
+	return manifest.ImportPath, pins, nil
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLegacyParsers(t *testing.T) {
+	tests := []struct {
+		manifest   string
+		files      map[string]string
+		wantModule string
+		wantPins   []legacyPin
+	}{
+		{
+			manifest: "Gopkg.lock",
+			files: map[string]string{"Gopkg.lock": `# This file is autogenerated, do not edit; changes may be undone by the next 'dep ensure'.
+
+[[projects]]
+  digest = "1:abc"
+  name = "github.com/pkg/errors"
+  packages = ["."]
+  revision = "645ef00459ed84a119197bfb8d8205042c6df63d"
+  version = "v0.8.0"
+
+[[projects]]
+  branch = "master"
+  name = "golang.org/x/sys"
+  packages = ["unix"]
+  revision = "c4afb3effaa53fd9a06ca61262dc7ce8df4c081b"
+
+[solve-meta]
+  analyzer-name = "dep"
+  input-imports = ["github.com/pkg/errors"]
+`},
+			wantPins: []legacyPin{
+				{Path: "github.com/pkg/errors", Revision: "645ef00459ed84a119197bfb8d8205042c6df63d", Version: "v0.8.0"},
+				{Path: "golang.org/x/sys", Revision: "c4afb3effaa53fd9a06ca61262dc7ce8df4c081b"},
+			},
+		},
+		{
+			manifest: "glide.lock",
+			files: map[string]string{
+				"glide.yaml": "package: github.com/example/app\nimport:\n- package: github.com/sirupsen/logrus\n",
+				"glide.lock": `hash: 1234
+updated: 2018-01-01T00:00:00Z
+imports:
+- name: github.com/sirupsen/logrus
+  version: d682213848ed68c0a260ca37d6dd5ace8423f5ba
+- name: gopkg.in/yaml.v2
+  version: 5420a8b6744d3b0345ab293f6fcba19c978f1183
+  subpackages:
+  - internal
+testImports:
+- name: github.com/stretchr/testify
+  version: 12b6f73e6084dad08a7c6e575284b177ecafbc71
+`,
+			},
+			wantModule: "github.com/example/app",
+			wantPins: []legacyPin{
+				{Path: "github.com/sirupsen/logrus", Revision: "d682213848ed68c0a260ca37d6dd5ace8423f5ba"},
+				{Path: "gopkg.in/yaml.v2", Revision: "5420a8b6744d3b0345ab293f6fcba19c978f1183"},
+				{Path: "github.com/stretchr/testify", Revision: "12b6f73e6084dad08a7c6e575284b177ecafbc71"},
+			},
+		},
+		{
+			manifest: "vendor/vendor.json",
+			files: map[string]string{"vendor/vendor.json": `{
+	"rootPath": "github.com/example/app",
+	"package": [
+		{"path": "github.com/gorilla/mux", "revision": "c5c6c98bc25355028a63748a498942a6398ccd22", "versionExact": "v1.6.2"},
+		{"path": "github.com/golang/protobuf/proto", "revision": "b4deda0973fb4c70b50d226b1af49f3da59f5265"}
+	]
+}`},
+			wantModule: "github.com/example/app",
+			wantPins: []legacyPin{
+				{Path: "github.com/gorilla/mux", Revision: "c5c6c98bc25355028a63748a498942a6398ccd22", Version: "v1.6.2"},
+				{Path: "github.com/golang/protobuf/proto", Revision: "b4deda0973fb4c70b50d226b1af49f3da59f5265"},
+			},
+		},
+		{
+			manifest: "Godeps/Godeps.json",
+			files: map[string]string{"Godeps/Godeps.json": `{
+	"ImportPath": "github.com/example/app",
+	"GoVersion": "go1.9",
+	"Deps": [
+		{"ImportPath": "github.com/davecgh/go-spew/spew", "Comment": "v1.1.0", "Rev": "346938d642f2ec3594ed81d874461961cd0faa76"},
+		{"ImportPath": "golang.org/x/net/context", "Rev": "a8b9294777976932365dabb6640cf1468d95c70f"}
+	]
+}`},
+			wantModule: "github.com/example/app",
+			wantPins: []legacyPin{
+				{Path: "github.com/davecgh/go-spew/spew", Revision: "346938d642f2ec3594ed81d874461961cd0faa76", Version: "v1.1.0"},
+				{Path: "golang.org/x/net/context", Revision: "a8b9294777976932365dabb6640cf1468d95c70f"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		var m legacyManifest
+		for _, lm := range legacyManifests {
+			if filepath.ToSlash(lm.file) == tt.manifest {
+				m = lm
+			}
+		}
+		if m.parse == nil {
+			t.Fatalf("no parser for %s", tt.manifest)
+		}
+		dir := t.TempDir()
+		writeFiles(t, dir, tt.files)
+		modulePath, pins, err := m.parse(dir)
+		if err != nil {
+			t.Errorf("%s: %v", tt.manifest, err)
+			continue
+		}
+		if modulePath != tt.wantModule || !reflect.DeepEqual(pins, tt.wantPins) {
+			t.Errorf("%s: got %q, %+v, want %q, %+v", tt.manifest, modulePath, pins, tt.wantModule, tt.wantPins)
+		}
+	}
+}
+
+func TestLegacyRepoRoot(t *testing.T) {
+	tests := []struct{ path, want string }{
+		{"github.com/pkg/errors", "github.com/pkg/errors"},
+		{"github.com/golang/protobuf/proto", "github.com/golang/protobuf"},
+		{"golang.org/x/net/context/ctxhttp", "golang.org/x/net"},
+		{"gopkg.in/yaml.v2", "gopkg.in/yaml.v2"},
+		{"gopkg.in/src-d/go-git.v4/plumbing", "gopkg.in/src-d/go-git.v4"},
+		{"k8s.io/client-go/kubernetes", "k8s.io/client-go/kubernetes"},
+	}
+	for _, tt := range tests {
+		if got := legacyRepoRoot(tt.path); got != tt.want {
+			t.Errorf("legacyRepoRoot(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestModulePathFromURL(t *testing.T) {
+	tests := []struct{ url, want string }{
+		{"https://github.com/example/app.git", "github.com/example/app"},
+		{"https://github.com/example/app/", "github.com/example/app"},
+		{"git@github.com:example/app.git", "github.com/example/app"},
+		{"/home/me/src/app", "app"},
+	}
+	for _, tt := range tests {
+		if got := modulePathFromURL(tt.url); got != tt.want {
+			t.Errorf("modulePathFromURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+// writeFileProxy lays out a GOPROXY=file:// tree serving each module
+// version with an empty go.mod and a zip holding a package at the module
+// root. Revisions map to the pseudo-version their .info file reports.
+func writeFileProxy(t *testing.T, versions map[string][]string, revisions map[string]map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	files := make(map[string]string)
+	for path, vs := range versions {
+		files[path+"/@v/list"] = strings.Join(vs, "\n") + "\n"
+		for _, v := range vs {
+			files[path+"/@v/"+v+".info"] = `{"Version":"` + v + `","Time":"2020-01-01T00:00:00Z"}`
+			files[path+"/@v/"+v+".mod"] = "module " + path + "\n"
+		}
+	}
+	for path, revs := range revisions {
+		for rev, v := range revs {
+			files[path+"/@v/"+rev+".info"] = `{"Version":"` + v + `","Time":"2020-01-01T00:00:00Z"}`
+		}
+	}
+	writeFiles(t, root, files)
+	for path, vs := range versions {
+		for _, v := range vs {
+			f, err := os.Create(filepath.Join(root, path, "@v", v+".zip"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			zw := zip.NewWriter(f)
+			w, err := zw.Create(path + "@" + v + "/go.mod")
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte("module " + path + "\n"))
+			if w, err = zw.Create(path + "@" + v + "/doc.go"); err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte("package " + filepath.Base(path) + "\n"))
+			if err := zw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+		}
+	}
+	return root
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	proxy := writeFileProxy(t,
+		map[string][]string{
+			"github.com/pkg/errors":  {"v0.8.0"},
+			"github.com/golang/mock": {"v0.0.0-20190101000000-abcdef123456"},
+		},
+		map[string]map[string]string{
+			"github.com/golang/mock": {"abcdef123456": "v0.0.0-20190101000000-abcdef123456"},
+		})
+	t.Setenv("GOPROXY", "file://"+filepath.ToSlash(proxy))
+	t.Setenv("GOSUMDB", "off")
+	t.Setenv("GONOSUMDB", "")
+	t.Setenv("GOPRIVATE", "")
+	t.Setenv("GOFLAGS", "-modcacherw")
+	t.Setenv("GOMODCACHE", t.TempDir())
+	t.Setenv("TMPDIR", t.TempDir())
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"Gopkg.lock": `[[projects]]
+  name = "github.com/pkg/errors"
+  revision = "645ef00459ed84a119197bfb8d8205042c6df63d"
+  version = "v0.8.0"
+
+[[projects]]
+  name = "github.com/golang/mock"
+  packages = ["gomock"]
+  revision = "abcdef123456"
+
+[[projects]]
+  name = "github.com/golang/mock/mockgen"
+  revision = "abcdef123456"
+
+[[projects]]
+  name = "github.com/gone/away"
+  revision = "0123456789ab"
+`,
+		"vendor/github.com/pkg/errors/errors.go": "package errors\n",
+		"main.go":                                "package main\n\nfunc main() {}\n",
+	})
+
+	goModPath, err := migrateLegacy(dir, "https://github.com/example/app.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainModule, _, _, err := parseGoMod(goModPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mainModule != "github.com/example/app" {
+		t.Errorf("module path = %q, want github.com/example/app", mainModule)
+	}
+	data, err := os.ReadFile(goModPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"github.com/pkg/errors v0.8.0",
+		"github.com/golang/mock v0.0.0-20190101000000-abcdef123456",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("go.mod is missing %q:\n%s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "gone/away") || strings.Count(string(data), "golang/mock") != 1 {
+		t.Errorf("go.mod should require each resolvable repository once:\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "_vendor", "github.com", "pkg", "errors", "errors.go")); err != nil {
+		t.Errorf("vendor directory not moved aside: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "vendor")); !os.IsNotExist(err) {
+		t.Errorf("vendor directory still present: %v", err)
+	}
+}
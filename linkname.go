@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/version"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type LinknameUse struct {
+	File   string `json:"File"`
+	Line   int    `json:"Line"`
+	Local  string `json:"Local"`
+	Target string `json:"Target"`
+	Kind   string `json:"Kind"`
+	Status string `json:"Status"`
+}
+
+type ModuleLinknames struct {
+	Path    string        `json:"Path"`
+	Version string        `json:"Version,omitempty"`
+	Uses    []LinknameUse `json:"Uses"`
+	Failing int           `json:"Failing"`
+	Update  string        `json:"Update,omitempty"`
+	// UpdateFixes is "yes", "no" or empty when the update was not checked.
+	UpdateFixes string `json:"UpdateFixes,omitempty"`
+}
+
+type LinknameReport struct {
+	Toolchain string            `json:"Toolchain"`
+	Target    string            `json:"Target"`
+	Modules   []ModuleLinknames `json:"Modules"`
+}
+
+// stdLinknames is the standard library's side of the go:linkname rules the
+// linker enforces since go 1.23: a reference into std is only allowed if
+// the symbol is marked with a go:linkname there, and some symbols are
+// blocked for every package but the listed ones, or for all of them.
+type stdLinknames struct {
+	pushed  map[string]bool
+	blocked map[string][]string
+}
+
+func linknameCommand(fs *flag.FlagSet) func() error {
+	target := fs.String("go", "", "target Go version (default: the installed toolchain)")
+	format := fs.String("format", "text", "output format: text or json")
+	return func() error { return runLinkname(fs, *target, *format) }
+}
+
+func runLinkname(fs *flag.FlagSet, target, format string) error {
+	if fs.NArg() != 1 || (format != "text" && format != "json") {
+		return errUsage
+	}
+	out, err := runGo(os.TempDir(), "env", "GOVERSION", "GOROOT")
+	if err != nil {
+		return fmt.Errorf("error locating the Go toolchain: %v", err)
+	}
+	env := strings.Fields(string(out))
+	if len(env) != 2 {
+		return fmt.Errorf("error locating the Go toolchain: unexpected go env output %q", out)
+	}
+	toolchain, goroot := env[0], env[1]
+	if target == "" {
+		target = toolchain
+	}
+	if !strings.HasPrefix(target, "go") {
+		target = "go" + target
+	}
+	if !version.IsValid(target) {
+		return fmt.Errorf("invalid go version %q", target)
+	}
+	if version.Lang(target) != version.Lang(toolchain) {
+		fmt.Fprintf(os.Stderr, "warning: checking against the linkname rules of %s, not %s\n", toolchain, target)
+	}
+
+	dir, goModPath, err := checkoutRepo(fs.Arg(0))
+	if dir != "" {
+		defer os.RemoveAll(dir)
+	}
+	if err != nil {
+		return fmt.Errorf("error preparing repository: %v", err)
+	}
+	modDir := filepath.Dir(goModPath)
+	if _, err := runGo(modDir, "mod", "download"); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	modules, err := listModules(modDir, true)
+	if err != nil {
+		return fmt.Errorf("error listing modules: %v", err)
+	}
+	std, err := loadStdLinknames(goroot)
+	if err != nil {
+		return fmt.Errorf("error reading the standard library: %v", err)
+	}
+
+	report, err := checkLinknames(modules, std, toolchain, target)
+	if err != nil {
+		return fmt.Errorf("error checking go:linkname directives: %v", err)
+	}
+	if format == "json" {
+		return printJSON(report)
+	}
+	printLinknameReport(report)
+	return nil
+}
+
+// checkLinknames scans every module of the build list for go:linkname
+// directives and, for modules that will fail to link on target, checks
+// whether their available update still does.
+func checkLinknames(modules []ModuleInfo, std *stdLinknames, toolchain, target string) (*LinknameReport, error) {
+	sp := startSpan("linkname check", "go.target", target)
+	defer sp.End(nil)
+	report := &LinknameReport{Toolchain: toolchain, Target: target}
+	for _, m := range modules {
+		if m.Dir == "" {
+			continue
+		}
+		uses, err := scanLinknames(m.Dir, target, std)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", m.Path, err)
+		}
+		if len(uses) == 0 {
+			continue
+		}
+		ml := ModuleLinknames{Path: m.Path, Version: m.Version, Uses: uses, Failing: failingLinknames(uses)}
+		if ml.Failing > 0 && m.Update != nil {
+			ml.Update = m.Update.Version
+			if info, err := downloadModule(m.Update.Path, m.Update.Version); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: could not check %s@%s: %v\n", m.Update.Path, m.Update.Version, err)
+			} else if updated, err := scanLinknames(info.Dir, target, std); err == nil {
+				ml.UpdateFixes = "yes"
+				if failingLinknames(updated) > 0 {
+					ml.UpdateFixes = "no"
+				}
+			}
+		}
+		report.Modules = append(report.Modules, ml)
+	}
+	return report, nil
+}
+
+func failingLinknames(uses []LinknameUse) int {
+	n := 0
+	for _, u := range uses {
+		if u.Status != "allowed" {
+			n++
+		}
+	}
+	return n
+}
+
+// loadStdLinknames collects the symbols the standard library of goroot
+// marks with go:linkname, and the linker's list of blocked linknames.
+func loadStdLinknames(goroot string) (*stdLinknames, error) {
+	std := &stdLinknames{pushed: make(map[string]bool), blocked: make(map[string][]string)}
+	src := filepath.Join(goroot, "src")
+	err := filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); name == "testdata" || (path == filepath.Join(src, "cmd")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		rel, err := filepath.Rel(src, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		pkg := filepath.ToSlash(rel)
+		targets := make(map[string]string)
+		err = scanLinknameDirectives(path, func(line int, local, target string) {
+			if target == "" {
+				std.pushed[pkg+"."+local] = true
+			} else {
+				targets[local] = target
+			}
+		})
+		if err != nil || len(targets) == 0 {
+			return err
+		}
+		// Without a body the standard library pulls the symbol itself,
+		// which does not make it available to anyone else.
+		bodies := linknameDefinitions(path)
+		for local, target := range targets {
+			if bodies[local] {
+				std.pushed[target] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	loader := filepath.Join(src, "cmd", "link", "internal", "loader", "loader.go")
+	file, err := parser.ParseFile(token.NewFileSet(), loader, nil, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: blocked linknames unknown: %v\n", err)
+		return std, nil
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok || len(spec.Names) != 1 || spec.Names[0].Name != "blockedLinknames" || len(spec.Values) != 1 {
+			return true
+		}
+		lit, ok := spec.Values[0].(*ast.CompositeLit)
+		if !ok {
+			return false
+		}
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			key, ok := kv.Key.(*ast.BasicLit)
+			if !ok {
+				continue
+			}
+			name, _ := strconv.Unquote(key.Value)
+			var allowed []string
+			if pkgs, ok := kv.Value.(*ast.CompositeLit); ok {
+				for _, p := range pkgs.Elts {
+					if lit, ok := p.(*ast.BasicLit); ok {
+						s, _ := strconv.Unquote(lit.Value)
+						allowed = append(allowed, s)
+					}
+				}
+			}
+			std.blocked[name] = allowed
+		}
+		return false
+	})
+	return std, nil
+}
+
+// scanLinknameDirectives calls fn for each go:linkname directive in the
+// file at path; target is empty for the one-argument form.
+func scanLinknameDirectives(path string, fn func(line int, local, target string)) error {
+	data, err := os.ReadFile(path)
+	if err != nil || !bytes.Contains(data, []byte("//go:linkname ")) {
+		return err
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		rest, ok := strings.CutPrefix(line, "//go:linkname ")
+		if !ok {
+			continue
+		}
+		switch fields := strings.Fields(rest); len(fields) {
+		case 1:
+			fn(i+1, fields[0], "")
+		case 2:
+			fn(i+1, fields[0], fields[1])
+		}
+	}
+	return nil
+}
+
+// scanLinknames finds the go:linkname directives in the files of the
+// module at root that are built for the target Go version on this platform,
+// and classifies the references into the standard library.
+func scanLinknames(root, target string, std *stdLinknames) ([]LinknameUse, error) {
+	ctxt := build.Default
+	ctxt.ReleaseTags = nil
+	for minor := 1; minor <= goMinor(strings.TrimPrefix(target, "go")); minor++ {
+		ctxt.ReleaseTags = append(ctxt.ReleaseTags, fmt.Sprintf("go1.%d", minor))
+	}
+	restricted := version.Compare(target, "go1.23") >= 0
+
+	var uses []LinknameUse
+	err := walkModuleGoFiles(root, func(path string) error {
+		if strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		if ok, err := ctxt.MatchFile(filepath.Dir(path), filepath.Base(path)); err != nil || !ok {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		var found []LinknameUse
+		err := scanLinknameDirectives(path, func(line int, local, target string) {
+			if target != "" {
+				found = append(found, LinknameUse{File: filepath.ToSlash(rel), Line: line, Local: local, Target: target})
+			}
+		})
+		if err != nil || len(found) == 0 {
+			return err
+		}
+		bodies := linknameDefinitions(path)
+		for _, u := range found {
+			u.Kind = "pull"
+			if bodies[u.Local] {
+				u.Kind = "push"
+			}
+			u.Status = std.classify(u, restricted)
+			uses = append(uses, u)
+		}
+		return nil
+	})
+	return uses, err
+}
+
+// linknameDefinitions reports which functions of the file have a body; a
+// go:linkname on them pushes the definition instead of pulling a symbol.
+func linknameDefinitions(path string) map[string]bool {
+	bodies := make(map[string]bool)
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return bodies
+	}
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Body != nil {
+			bodies[fn.Name.Name] = true
+		}
+	}
+	return bodies
+}
+
+func (std *stdLinknames) classify(u LinknameUse, restricted bool) string {
+	if !restricted {
+		return "allowed"
+	}
+	if pkgs, ok := std.blocked[u.Target]; ok {
+		if len(pkgs) == 0 {
+			return "blocked for all packages"
+		}
+		return fmt.Sprintf("blocked by the linker outside %s", strings.Join(pkgs, ", "))
+	}
+	slash := strings.LastIndex(u.Target, "/")
+	dot := strings.Index(u.Target[slash+1:], ".")
+	if dot < 0 {
+		return "allowed"
+	}
+	pkg := u.Target[:slash+1+dot]
+	if strings.Contains(strings.SplitN(pkg, "/", 2)[0], ".") || u.Kind == "push" || std.pushed[u.Target] {
+		return "allowed"
+	}
+	return "not marked for go:linkname by the standard library"
+}
+
+func printLinknameReport(r *LinknameReport) {
+	fmt.Printf("go:linkname check for %s (rules of %s)\n", r.Target, r.Toolchain)
+	if version.Compare(r.Target, "go1.23") < 0 {
+		fmt.Println("The linker does not restrict go:linkname before go1.23.")
+	}
+	var failing, allowed []ModuleLinknames
+	for _, m := range r.Modules {
+		if m.Failing > 0 {
+			failing = append(failing, m)
+		} else {
+			allowed = append(allowed, m)
+		}
+	}
+	if len(failing) == 0 {
+		fmt.Println("No module uses a go:linkname that fails to link.")
+	} else {
+		fmt.Println("Modules that fail to link (or need -ldflags=-checklinkname=0):")
+		for _, m := range failing {
+			update := ""
+			switch m.UpdateFixes {
+			case "yes":
+				update = fmt.Sprintf("; fixed by update to %s", m.Update)
+			case "no":
+				update = fmt.Sprintf("; still failing in update %s", m.Update)
+			}
+			fmt.Printf("- %s: %d of %d go:linkname references%s\n", strings.TrimSpace(m.Path+" "+m.Version), m.Failing, len(m.Uses), update)
+			for _, u := range m.Uses {
+				if u.Status != "allowed" {
+					fmt.Printf("    %s:%d: %s %s: %s\n", u.File, u.Line, u.Kind, u.Target, u.Status)
+				}
+			}
+		}
+	}
+	for _, m := range allowed {
+		fmt.Printf("- %s: %d allowed go:linkname references\n", strings.TrimSpace(m.Path+" "+m.Version), len(m.Uses))
+	}
+}
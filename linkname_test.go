@@ -0,0 +1,165 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testStdLinknames() *stdLinknames {
+	return &stdLinknames{
+		pushed:  map[string]bool{"runtime.fastrand": true, "time.now": true},
+		blocked: map[string][]string{"runtime.coroswitch": {"iter"}, "runtime.unique_runtime_registerUniqueMapCleanup": nil},
+	}
+}
+
+func TestClassifyLinkname(t *testing.T) {
+	std := testStdLinknames()
+	tests := []struct {
+		use        LinknameUse
+		restricted bool
+		want       string
+	}{
+		{LinknameUse{Target: "runtime.nanotime1", Kind: "pull"}, false, "allowed"},
+		{LinknameUse{Target: "runtime.coroswitch", Kind: "pull"}, false, "allowed"},
+		{LinknameUse{Target: "runtime.fastrand", Kind: "pull"}, true, "allowed"},
+		{LinknameUse{Target: "time.now", Kind: "pull"}, true, "allowed"},
+		{LinknameUse{Target: "runtime.nanotime1", Kind: "pull"}, true, "not marked for go:linkname by the standard library"},
+		{LinknameUse{Target: "internal/poll.runtime_pollOpen", Kind: "pull"}, true, "not marked for go:linkname by the standard library"},
+		{LinknameUse{Target: "runtime.coroswitch", Kind: "pull"}, true, "blocked by the linker outside iter"},
+		{LinknameUse{Target: "runtime.coroswitch", Kind: "push"}, true, "blocked by the linker outside iter"},
+		{LinknameUse{Target: "runtime.unique_runtime_registerUniqueMapCleanup", Kind: "pull"}, true, "blocked for all packages"},
+		{LinknameUse{Target: "runtime.myHook", Kind: "push"}, true, "allowed"},
+		{LinknameUse{Target: "github.com/a/b.helper", Kind: "pull"}, true, "allowed"},
+		{LinknameUse{Target: "github.com/a/b/v2.(*T).method", Kind: "pull"}, true, "allowed"},
+		{LinknameUse{Target: "localsymbol", Kind: "pull"}, true, "allowed"},
+	}
+	for _, tt := range tests {
+		if got := std.classify(tt.use, tt.restricted); got != tt.want {
+			t.Errorf("classify(%s %s, restricted %v) = %q, want %q", tt.use.Kind, tt.use.Target, tt.restricted, got, tt.want)
+		}
+	}
+}
+
+func TestLoadStdLinknames(t *testing.T) {
+	goroot := t.TempDir()
+	writeFiles(t, goroot, map[string]string{
+		"src/runtime/rand.go": `package runtime
+
+//go:linkname fastrand
+func fastrand() uint32 { return 0 }
+
+//go:linkname sync_fastrandn sync.fastrandn
+func sync_fastrandn(n uint32) uint32 { return 0 }
+`,
+		"src/internal/poll/fd.go":       "package poll\n\n//go:linkname runtime_Semacquire\nfunc runtime_Semacquire(*uint32)\n",
+		"src/runtime/rand_test.go":      "package runtime\n\n//go:linkname testOnly\nfunc testOnly() {}\n",
+		"src/runtime/testdata/x/x.go":   "package x\n\n//go:linkname fixture\nfunc fixture() {}\n",
+		"src/cmd/compile/internal/c.go": "package c\n\n//go:linkname compilerOnly\nfunc compilerOnly() {}\n",
+		// A pull by the standard library itself does not mark the target.
+		"src/time/sleep.go": "package time\n\n//go:linkname runtimeNano runtime.nanotime\nfunc runtimeNano() int64\n",
+		"src/cmd/link/internal/loader/loader.go": `package loader
+
+var blockedLinknames = map[string][]string{
+	// coroutines
+	"runtime.coroswitch": {"iter"},
+	"runtime.newcoro":    {"iter"},
+	// weak pointers
+	"internal/weak.runtime_registerWeakPointer": {"internal/weak"},
+	"runtime.unique_runtime_registerUniqueMapCleanup": nil,
+}
+`,
+	})
+	std, err := loadStdLinknames(goroot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPushed := map[string]bool{"runtime.fastrand": true, "sync.fastrandn": true, "internal/poll.runtime_Semacquire": true}
+	if !reflect.DeepEqual(std.pushed, wantPushed) {
+		t.Errorf("pushed = %v, want %v", std.pushed, wantPushed)
+	}
+	wantBlocked := map[string][]string{
+		"runtime.coroswitch":                              {"iter"},
+		"runtime.newcoro":                                 {"iter"},
+		"internal/weak.runtime_registerWeakPointer":       {"internal/weak"},
+		"runtime.unique_runtime_registerUniqueMapCleanup": nil,
+	}
+	if !reflect.DeepEqual(std.blocked, wantBlocked) {
+		t.Errorf("blocked = %v, want %v", std.blocked, wantBlocked)
+	}
+}
+
+func TestScanLinknames(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.mod": "module example.com/m\n",
+		"hooks.go": `package m
+
+import _ "unsafe"
+
+//go:linkname nanotime runtime.nanotime1
+func nanotime() int64
+
+//go:linkname fastrand runtime.fastrand
+func fastrand() uint32
+
+//go:linkname coroswitch runtime.coroswitch
+func coroswitch(c any)
+
+//go:linkname exported runtime.myHook
+func exported() {}
+
+//go:linkname local
+func local() {}
+`,
+		"future.go":      "//go:build go1.99\n\npackage m\n\n//go:linkname future runtime.future\nfunc future()\n",
+		"hooks_test.go":  "package m\n\n//go:linkname testHook runtime.testHook\nfunc testHook()\n",
+		"vendor/v/v.go":  "package v\n\n//go:linkname vendored runtime.vendored\nfunc vendored()\n",
+		"nested/go.mod":  "module example.com/m/nested\n",
+		"nested/n.go":    "package n\n\n//go:linkname nested runtime.nested\nfunc nested() {}\n",
+		"plain/go.mod":   "module example.com/m/plain\n",
+		"plain/p.go":     "package p\n",
+		"sub/ignored.go": "//go:build ignore\n\npackage sub\n\n//go:linkname ignored runtime.ignored\nfunc ignored()\n",
+		"sub/polling.go": "package sub\n\n//go:linkname open internal/poll.runtime_pollOpen\nfunc open(fd uintptr) (uintptr, int)\n",
+	})
+	std := testStdLinknames()
+
+	uses, err := scanLinknames(root, "go1.23", std)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []LinknameUse{
+		{File: "hooks.go", Line: 5, Local: "nanotime", Target: "runtime.nanotime1", Kind: "pull", Status: "not marked for go:linkname by the standard library"},
+		{File: "hooks.go", Line: 8, Local: "fastrand", Target: "runtime.fastrand", Kind: "pull", Status: "allowed"},
+		{File: "hooks.go", Line: 11, Local: "coroswitch", Target: "runtime.coroswitch", Kind: "pull", Status: "blocked by the linker outside iter"},
+		{File: "hooks.go", Line: 14, Local: "exported", Target: "runtime.myHook", Kind: "push", Status: "allowed"},
+		{File: "sub/polling.go", Line: 3, Local: "open", Target: "internal/poll.runtime_pollOpen", Kind: "pull", Status: "not marked for go:linkname by the standard library"},
+	}
+	if !reflect.DeepEqual(uses, want) {
+		t.Errorf("uses =\n%+v\nwant\n%+v", uses, want)
+	}
+	if n := failingLinknames(uses); n != 3 {
+		t.Errorf("failing = %d, want 3", n)
+	}
+
+	uses, err = scanLinknames(root, "go1.22", std)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uses) != len(want) || failingLinknames(uses) != 0 {
+		t.Errorf("go1.22 uses = %+v, want %d uses, all allowed", uses, len(want))
+	}
+
+	report, err := checkLinknames([]ModuleInfo{
+		{Path: "example.com/m", Version: "v1.0.0", Dir: root},
+		{Path: "example.com/m/nested", Version: "v1.0.0", Dir: filepath.Join(root, "nested")},
+		{Path: "example.com/m/plain", Version: "v1.0.0", Dir: filepath.Join(root, "plain")},
+		{Path: "example.com/missing", Version: "v1.0.0"},
+	}, std, "go1.23.0", "go1.23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Modules) != 2 || report.Modules[0].Failing != 3 || report.Modules[1].Path != "example.com/m/nested" || report.Modules[1].Failing != 0 {
+		t.Errorf("report = %+v", report)
+	}
+}
@@ -0,0 +1,694 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	lspSeverityError       = 1
+	lspSeverityWarning     = 2
+	lspSeverityInformation = 3
+	maxVersionActions      = 5
+	publishDelay           = 300 * time.Millisecond
+)
+
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code,omitempty"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspCodeAction struct {
+	Title       string          `json:"title"`
+	Kind        string          `json:"kind"`
+	Diagnostics []lspDiagnostic `json:"diagnostics,omitempty"`
+	IsPreferred bool            `json:"isPreferred,omitempty"`
+	Edit        struct {
+		Changes map[string][]lspTextEdit `json:"changes"`
+	} `json:"edit"`
+}
+
+type textDocumentParams struct {
+	TextDocument struct {
+		URI     string `json:"uri"`
+		Version int    `json:"version"`
+		Text    string `json:"text"`
+	} `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+	Position lspPosition `json:"position"`
+	Range    lspRange    `json:"range"`
+}
+
+type lspServer struct {
+	in          *bufio.Reader
+	out         io.Writer
+	writeMu     sync.Mutex
+	mu          sync.Mutex
+	docs        map[string]string
+	docVersions map[string]int
+	runs        map[string]*publishRun
+	modules     map[string]map[string]ModuleInfo
+	versions    map[string][]string
+	vulns       *vulnDB
+	nextID      int
+	// delay is how long edits must pause before diagnostics are computed.
+	delay time.Duration
+	// published holds the diagnostics last sent for each document, so code
+	// actions can refer to them without recomputing.
+	published map[string][]lspDiagnostic
+}
+
+// publishRun is the pending or running diagnostics pass for one document.
+// Each change bumps gen, so a pass that finishes after a newer change was
+// scheduled drops its result instead of publishing stale diagnostics.
+type publishRun struct {
+	gen    int
+	timer  *time.Timer
+	cancel context.CancelFunc
+}
+
+func newLSPServer(in io.Reader, out io.Writer) *lspServer {
+	return &lspServer{
+		in:          bufio.NewReader(in),
+		out:         out,
+		docs:        make(map[string]string),
+		docVersions: make(map[string]int),
+		runs:        make(map[string]*publishRun),
+		modules:     make(map[string]map[string]ModuleInfo),
+		versions:    make(map[string][]string),
+		vulns:       newVulnDB(),
+		delay:       publishDelay,
+		published:   make(map[string][]lspDiagnostic),
+	}
+}
+
+func lspCommand(fs *flag.FlagSet) func() error {
+	return func() error { return runLSP(fs) }
+}
+
+func runLSP(fs *flag.FlagSet) error {
+	if fs.NArg() > 0 {
+		return errUsage
+	}
+	log.SetOutput(os.Stderr)
+	if err := newLSPServer(os.Stdin, os.Stdout).serve(); err != nil {
+		return fmt.Errorf("error serving LSP: %v", err)
+	}
+	return nil
+}
+
+func (s *lspServer) serve() error {
+	for {
+		msg, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "" {
+			continue
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		result, rerr := s.handle(msg)
+		if msg.ID == nil {
+			continue
+		}
+		resp := rpcMessage{JSONRPC: "2.0", ID: msg.ID}
+		if rerr != nil {
+			resp.Error = rerr
+		} else if resp.Result, err = json.Marshal(result); err != nil {
+			resp.Error = &rpcError{Code: -32603, Message: err.Error()}
+			resp.Result = nil
+		}
+		if err := s.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *lspServer) read() (*rpcMessage, error) {
+	length := -1
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %v", err)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+	var msg rpcMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (s *lspServer) write(msg rpcMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return err
+}
+
+func (s *lspServer) send(method string, params any, request bool) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		log.Printf("lsp: %v", err)
+		return
+	}
+	msg := rpcMessage{JSONRPC: "2.0", Method: method, Params: data}
+	if request {
+		s.mu.Lock()
+		s.nextID++
+		msg.ID = json.RawMessage(strconv.Itoa(s.nextID))
+		s.mu.Unlock()
+	}
+	if err := s.write(msg); err != nil {
+		log.Printf("lsp: %v", err)
+	}
+}
+
+func (s *lspServer) handle(msg *rpcMessage) (any, *rpcError) {
+	var p textDocumentParams
+	if len(msg.Params) > 0 {
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, &rpcError{Code: -32602, Message: err.Error()}
+		}
+	}
+	uri := p.TextDocument.URI
+
+	switch msg.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync": map[string]any{"openClose": true, "change": 1, "save": map[string]any{}},
+				"hoverProvider":    true,
+				"codeActionProvider": map[string]any{
+					"codeActionKinds": []string{"quickfix"},
+				},
+			},
+			"serverInfo": map[string]any{"name": "go-dep-analysis"},
+		}, nil
+	case "initialized":
+		s.send("client/registerCapability", map[string]any{
+			"registrations": []map[string]any{{
+				"id":     "go-mod-watcher",
+				"method": "workspace/didChangeWatchedFiles",
+				"registerOptions": map[string]any{
+					"watchers": []map[string]any{{"globPattern": "**/go.mod"}},
+				},
+			}},
+		}, true)
+	case "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		s.setDoc(uri, p.TextDocument.Text, p.TextDocument.Version)
+		s.schedulePublish(uri)
+	case "textDocument/didChange":
+		if n := len(p.ContentChanges); n > 0 {
+			s.setDoc(uri, p.ContentChanges[n-1].Text, p.TextDocument.Version)
+			s.schedulePublish(uri)
+		}
+	case "textDocument/didSave":
+		s.invalidate(uri)
+		s.schedulePublish(uri)
+	case "textDocument/didClose":
+		s.mu.Lock()
+		delete(s.docs, uri)
+		delete(s.docVersions, uri)
+		s.mu.Unlock()
+		s.clearDiagnostics(uri)
+	case "workspace/didChangeWatchedFiles":
+		var w struct {
+			Changes []struct {
+				URI  string `json:"uri"`
+				Type int    `json:"type"`
+			} `json:"changes"`
+		}
+		json.Unmarshal(msg.Params, &w)
+		for _, c := range w.Changes {
+			s.invalidate(c.URI)
+			if c.Type == 3 {
+				s.clearDiagnostics(c.URI)
+				continue
+			}
+			s.schedulePublish(c.URI)
+		}
+	case "textDocument/hover":
+		return s.hover(uri, p.Position), nil
+	case "textDocument/codeAction":
+		return s.codeActions(uri, p.Range), nil
+	default:
+		if msg.ID != nil && !strings.HasPrefix(msg.Method, "$/") {
+			return nil, &rpcError{Code: -32601, Message: "method not found: " + msg.Method}
+		}
+	}
+	return nil, nil
+}
+
+func (s *lspServer) setDoc(uri, text string, version int) {
+	s.mu.Lock()
+	s.docs[uri] = text
+	s.docVersions[uri] = version
+	s.mu.Unlock()
+}
+
+func (s *lspServer) text(uri string) (string, error) {
+	s.mu.Lock()
+	text, ok := s.docs[uri]
+	s.mu.Unlock()
+	if ok {
+		return text, nil
+	}
+	data, err := os.ReadFile(uriToPath(uri))
+	return string(data), err
+}
+
+func (s *lspServer) invalidate(uri string) {
+	s.mu.Lock()
+	delete(s.modules, filepath.Dir(uriToPath(uri)))
+	s.mu.Unlock()
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func (s *lspServer) moduleInfo(dir string) map[string]ModuleInfo {
+	s.mu.Lock()
+	mods, ok := s.modules[dir]
+	s.mu.Unlock()
+	if ok {
+		return mods
+	}
+	list, err := listModules(dir, true)
+	if err != nil {
+		log.Printf("lsp: %v", err)
+		return nil
+	}
+	mods = make(map[string]ModuleInfo, len(list))
+	for _, m := range list {
+		mods[m.Path] = m
+	}
+	s.mu.Lock()
+	s.modules[dir] = mods
+	s.mu.Unlock()
+	return mods
+}
+
+func (s *lspServer) availableVersions(dir, modPath string) []string {
+	s.mu.Lock()
+	versions, ok := s.versions[modPath]
+	s.mu.Unlock()
+	if ok {
+		return versions
+	}
+	out, err := runGo(dir, "list", "-m", "-versions", "-json", modPath)
+	if err != nil {
+		log.Printf("lsp: %v", err)
+		return nil
+	}
+	var m ModuleInfo
+	if err := json.Unmarshal(out, &m); err != nil {
+		return nil
+	}
+	s.mu.Lock()
+	s.versions[modPath] = m.Versions
+	s.mu.Unlock()
+	return m.Versions
+}
+
+func (s *lspServer) parse(uri string) (*modfile.File, []string, error) {
+	text, err := s.text(uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := modfile.Parse(uriToPath(uri), []byte(text), nil)
+	return f, strings.Split(text, "\n"), err
+}
+
+// schedulePublish computes diagnostics for uri once edits pause for
+// s.delay, cancelling any pass still pending or running for it.
+func (s *lspServer) schedulePublish(uri string) {
+	if filepath.Base(uriToPath(uri)) != "go.mod" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run := s.nextRun(uri)
+	ctx, cancel := context.WithCancel(context.Background())
+	run.cancel = cancel
+	gen := run.gen
+	run.timer = time.AfterFunc(s.delay, func() { s.publish(ctx, uri, gen) })
+}
+
+// nextRun stops the current pass for uri and starts a new generation.
+// s.mu must be held.
+func (s *lspServer) nextRun(uri string) *publishRun {
+	run := s.runs[uri]
+	if run == nil {
+		run = &publishRun{}
+		s.runs[uri] = run
+	}
+	if run.timer != nil {
+		run.timer.Stop()
+		run.cancel()
+	}
+	run.gen++
+	run.timer, run.cancel = nil, nil
+	return run
+}
+
+func (s *lspServer) clearDiagnostics(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextRun(uri)
+	delete(s.published, uri)
+	s.send("textDocument/publishDiagnostics", map[string]any{"uri": uri, "diagnostics": []lspDiagnostic{}}, false)
+}
+
+func (s *lspServer) publish(ctx context.Context, uri string, gen int) {
+	diags := s.diagnostics(ctx, uri)
+	if ctx.Err() != nil {
+		return
+	}
+	if diags == nil {
+		diags = []lspDiagnostic{}
+	}
+	params := map[string]any{"uri": uri, "diagnostics": diags}
+
+	// Holding s.mu until the notification is written keeps a newer pass
+	// from publishing first and then being overwritten by this one.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if run := s.runs[uri]; run == nil || run.gen != gen {
+		return
+	}
+	if v, ok := s.docVersions[uri]; ok {
+		params["version"] = v
+	}
+	s.published[uri] = diags
+	s.send("textDocument/publishDiagnostics", params, false)
+}
+
+func (s *lspServer) diagnostics(ctx context.Context, uri string) []lspDiagnostic {
+	f, lines, err := s.parse(uri)
+	if err != nil {
+		var errs modfile.ErrorList
+		if !errors.As(err, &errs) {
+			return []lspDiagnostic{{Severity: lspSeverityError, Source: "go-dep-analysis", Message: err.Error()}}
+		}
+		var diags []lspDiagnostic
+		for _, e := range errs {
+			line := max(e.Pos.Line-1, 0)
+			diags = append(diags, lspDiagnostic{
+				Range:    lspRange{Start: lspPosition{Line: line}, End: lspPosition{Line: line, Character: lineLength(lines, line)}},
+				Severity: lspSeverityError,
+				Source:   "go-dep-analysis",
+				Message:  e.Err.Error(),
+			})
+		}
+		return diags
+	}
+
+	mods := s.moduleInfo(filepath.Dir(uriToPath(uri)))
+	pins, _ := parsePins(f)
+	var diags []lspDiagnostic
+	var vulnErr error
+	for _, r := range f.Require {
+		if ctx.Err() != nil {
+			return nil
+		}
+		rng := requireRange(r, lines)
+		add := func(severity int, code, message string) {
+			diags = append(diags, lspDiagnostic{Range: rng, Severity: severity, Code: code, Source: "go-dep-analysis", Message: message})
+		}
+		info, ok := mods[r.Mod.Path]
+		if ok && info.Version == r.Mod.Version {
+			if len(info.Retracted) > 0 {
+				add(lspSeverityWarning, "retracted", fmt.Sprintf("%s %s is retracted: %s", r.Mod.Path, r.Mod.Version, strings.Join(info.Retracted, "; ")))
+			}
+		}
+		if ok && info.Deprecated != "" {
+			add(lspSeverityWarning, "deprecated", fmt.Sprintf("%s is deprecated: %s", r.Mod.Path, info.Deprecated))
+		}
+		var vulns []Vulnerability
+		if vulnErr == nil {
+			if vulns, vulnErr = s.vulns.Vulnerabilities(r.Mod.Path, r.Mod.Version); vulnErr != nil {
+				log.Printf("lsp: vulnerability lookup: %v", vulnErr)
+			}
+		}
+		for _, v := range vulns {
+			msg := fmt.Sprintf("%s %s is affected by %s", r.Mod.Path, r.Mod.Version, v.ID)
+			if v.Summary != "" {
+				msg += ": " + v.Summary
+			}
+			if v.Fixed != "" {
+				msg += " (fixed in " + v.Fixed + ")"
+			}
+			add(lspSeverityError, "vulnerable", msg)
+		}
+		if pin := pins[r.Mod.Path]; pin.Active(time.Now()) {
+			add(lspSeverityInformation, "pinned", fmt.Sprintf("%s is %s", r.Mod.Path, pin))
+		} else if ok && info.Update != nil && semver.Compare(info.Update.Version, r.Mod.Version) > 0 {
+			add(lspSeverityInformation, "outdated", fmt.Sprintf("%s can be upgraded to %s", r.Mod.Path, info.Update.Version))
+		}
+	}
+	return diags
+}
+
+func requireRange(r *modfile.Require, lines []string) lspRange {
+	start, end := r.Syntax.Start, r.Syntax.End
+	return lspRange{
+		Start: lspPosition{Line: start.Line - 1, Character: utf16Column(lines, start.Line-1, start.Byte-lineOffset(lines, start.Line-1))},
+		End:   lspPosition{Line: end.Line - 1, Character: utf16Column(lines, end.Line-1, end.Byte-lineOffset(lines, end.Line-1))},
+	}
+}
+
+func versionRange(r *modfile.Require, lines []string) (lspRange, bool) {
+	line := r.Syntax.Start.Line - 1
+	if line < 0 || line >= len(lines) {
+		return lspRange{}, false
+	}
+	text := lines[line]
+	from := strings.Index(text, r.Mod.Path)
+	if from < 0 {
+		return lspRange{}, false
+	}
+	from += len(r.Mod.Path)
+	i := strings.Index(text[from:], r.Mod.Version)
+	if i < 0 {
+		return lspRange{}, false
+	}
+	start := from + i
+	return lspRange{
+		Start: lspPosition{Line: line, Character: utf16Column(lines, line, start)},
+		End:   lspPosition{Line: line, Character: utf16Column(lines, line, start+len(r.Mod.Version))},
+	}, true
+}
+
+func lineOffset(lines []string, line int) int {
+	offset := 0
+	for i := 0; i < line && i < len(lines); i++ {
+		offset += len(lines[i]) + 1
+	}
+	return offset
+}
+
+func utf16Column(lines []string, line, byteCol int) int {
+	if line < 0 || line >= len(lines) {
+		return 0
+	}
+	text := lines[line]
+	if byteCol > len(text) {
+		byteCol = len(text)
+	}
+	return len(utf16.Encode([]rune(text[:max(byteCol, 0)])))
+}
+
+func lineLength(lines []string, line int) int {
+	if line >= len(lines) {
+		return 0
+	}
+	return utf16Column(lines, line, len(lines[line]))
+}
+
+func requireAt(f *modfile.File, line int) *modfile.Require {
+	for _, r := range f.Require {
+		if r.Syntax.Start.Line-1 <= line && line <= r.Syntax.End.Line-1 {
+			return r
+		}
+	}
+	return nil
+}
+
+func (s *lspServer) hover(uri string, pos lspPosition) any {
+	f, lines, err := s.parse(uri)
+	if err != nil {
+		return nil
+	}
+	r := requireAt(f, pos.Line)
+	if r == nil {
+		return nil
+	}
+	dir := filepath.Dir(uriToPath(uri))
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**%s** %s\n\n", r.Mod.Path, r.Mod.Version)
+	if info, ok := s.moduleInfo(dir)[r.Mod.Path]; ok {
+		if info.Version != r.Mod.Version {
+			fmt.Fprintf(&sb, "Selected version: %s\n\n", info.Version)
+		}
+		if info.Update != nil {
+			fmt.Fprintf(&sb, "Latest: %s\n\n", info.Update.Version)
+		}
+		if info.Deprecated != "" {
+			fmt.Fprintf(&sb, "Deprecated: %s\n\n", info.Deprecated)
+		}
+	}
+	if newer := newerVersions(s.availableVersions(dir, r.Mod.Path), r.Mod.Version); len(newer) > 0 {
+		fmt.Fprintf(&sb, "Newer versions: %s\n", strings.Join(newer, ", "))
+	} else {
+		sb.WriteString("No newer versions available.\n")
+	}
+	return map[string]any{
+		"contents": map[string]string{"kind": "markdown", "value": sb.String()},
+		"range":    requireRange(r, lines),
+	}
+}
+
+func newerVersions(versions []string, current string) []string {
+	var newer []string
+	for i := len(versions) - 1; i >= 0; i-- {
+		if semver.Compare(versions[i], current) > 0 {
+			newer = append(newer, versions[i])
+		}
+	}
+	return newer
+}
+
+func (s *lspServer) codeActions(uri string, rng lspRange) []lspCodeAction {
+	f, lines, err := s.parse(uri)
+	if err != nil {
+		return nil
+	}
+	dir := filepath.Dir(uriToPath(uri))
+	s.mu.Lock()
+	diags := s.published[uri]
+	s.mu.Unlock()
+	pins, _ := parsePins(f)
+	actions := []lspCodeAction{}
+	for _, r := range f.Require {
+		line := r.Syntax.Start.Line - 1
+		if line < rng.Start.Line || line > rng.End.Line || pins[r.Mod.Path].Active(time.Now()) {
+			continue
+		}
+		vr, ok := versionRange(r, lines)
+		if !ok {
+			continue
+		}
+		var related []lspDiagnostic
+		for _, d := range diags {
+			if d.Range.Start.Line == line {
+				related = append(related, d)
+			}
+		}
+		latest := ""
+		if info, ok := s.moduleInfo(dir)[r.Mod.Path]; ok && info.Update != nil {
+			latest = info.Update.Version
+		}
+		newer := newerVersions(s.availableVersions(dir, r.Mod.Path), r.Mod.Version)
+		if len(newer) > maxVersionActions {
+			newer = newer[:maxVersionActions]
+		}
+		if latest != "" && !slices.Contains(newer, latest) {
+			newer = append([]string{latest}, newer...)
+		}
+		for _, v := range newer {
+			action := lspCodeAction{
+				Title:       fmt.Sprintf("Upgrade %s to %s", r.Mod.Path, v),
+				Kind:        "quickfix",
+				Diagnostics: related,
+				IsPreferred: v == latest,
+			}
+			action.Edit.Changes = map[string][]lspTextEdit{uri: {{Range: vr, NewText: v}}}
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func lspNotify(t *testing.T, s *lspServer, method string, params any) {
+	t.Helper()
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, rerr := s.handle(&rpcMessage{JSONRPC: "2.0", Method: method, Params: data}); rerr != nil {
+		t.Fatalf("%s: %s", method, rerr.Message)
+	}
+}
+
+type publishedDiagnostics struct {
+	URI         string          `json:"uri"`
+	Version     int             `json:"version"`
+	Diagnostics []lspDiagnostic `json:"diagnostics"`
+}
+
+func readPublished(t *testing.T, out string) []publishedDiagnostics {
+	t.Helper()
+	s := &lspServer{in: bufio.NewReader(strings.NewReader(out))}
+	var published []publishedDiagnostics
+	for {
+		msg, err := s.read()
+		if err != nil {
+			break
+		}
+		if msg.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var p publishedDiagnostics
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			t.Fatal(err)
+		}
+		published = append(published, p)
+	}
+	return published
+}
+
+// firePending runs the diagnostics pass scheduled for uri now rather than
+// after s.delay.
+func firePending(t *testing.T, s *lspServer, uri string) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run := s.runs[uri]
+	if run == nil || run.timer == nil {
+		t.Fatalf("no diagnostics pass pending for %s", uri)
+	}
+	run.timer.Reset(0)
+}
+
+// waitPublished waits until out holds at least n diagnostics notifications.
+func waitPublished(t *testing.T, out *syncBuffer, n int) []publishedDiagnostics {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		published := readPublished(t, out.String())
+		if len(published) >= n || time.Now().After(deadline) {
+			return published
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLSPPublishDebounce(t *testing.T) {
+	out := &syncBuffer{}
+	s := newLSPServer(strings.NewReader(""), out)
+	// Edits never pause long enough on their own; firePending stands in
+	// for the pause.
+	s.delay = time.Hour
+	uri := "file://" + filepath.ToSlash(filepath.Join(t.TempDir(), "go.mod"))
+	doc := func(version int, text string) map[string]any {
+		return map[string]any{"textDocument": map[string]any{"uri": uri, "version": version, "text": text}}
+	}
+
+	// Unparsable documents keep diagnostics from reaching the go command.
+	lspNotify(t, s, "textDocument/didOpen", doc(1, "first error\n"))
+	for v := 2; v <= 4; v++ {
+		change := doc(v, "")
+		change["contentChanges"] = []map[string]string{{"text": "bogus " + strings.Repeat("x", v) + "\n"}}
+		lspNotify(t, s, "textDocument/didChange", change)
+	}
+	firePending(t, s, uri)
+
+	published := waitPublished(t, out, 1)
+	if len(published) != 1 {
+		t.Fatalf("published %d times, want once after edits pause: %+v", len(published), published)
+	}
+	if p := published[0]; p.Version != 4 || len(p.Diagnostics) == 0 {
+		t.Errorf("published %+v, want diagnostics for version 4", p)
+	}
+
+	// A pass scheduled before the document closes must not publish after
+	// the close cleared its diagnostics.
+	lspNotify(t, s, "textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": uri, "version": 5},
+		"contentChanges": []map[string]string{{"text": "still bogus\n"}},
+	})
+	lspNotify(t, s, "textDocument/didClose", doc(5, ""))
+
+	s.mu.Lock()
+	pending := s.runs[uri].timer != nil
+	_, kept := s.published[uri]
+	s.mu.Unlock()
+	if pending || kept {
+		t.Errorf("after close: pass pending %v, diagnostics kept %v", pending, kept)
+	}
+	published = readPublished(t, out.String())[1:]
+	if len(published) != 1 || len(published[0].Diagnostics) != 0 {
+		t.Errorf("after close published %+v, want only the cleared diagnostics", published)
+	}
+}
+
+func TestLSPCodeActionsUsePublished(t *testing.T) {
+	s := newLSPServer(strings.NewReader(""), &syncBuffer{})
+	dir := t.TempDir()
+	uri := "file://" + filepath.ToSlash(filepath.Join(dir, "go.mod"))
+	s.setDoc(uri, "module example.com/m\n\nrequire example.com/a v1.0.0\n", 1)
+	// Cached module data keeps the go command out of the test.
+	s.modules[dir] = map[string]ModuleInfo{
+		"example.com/a": withUpdate(ModuleInfo{Path: "example.com/a", Version: "v1.0.0"}, "v1.2.0", nil),
+	}
+	s.versions["example.com/a"] = []string{"v1.0.0", "v1.1.0", "v1.2.0"}
+	// A vulnerability lookup would fail the test: nothing may recompute
+	// diagnostics.
+	s.vulns = &vulnDB{err: errors.New("vulnerability database queried")}
+	diag := lspDiagnostic{Range: lspRange{Start: lspPosition{Line: 2}}, Severity: lspSeverityInformation, Code: "outdated", Message: "published"}
+	s.published[uri] = []lspDiagnostic{diag}
+
+	actions := s.codeActions(uri, lspRange{Start: lspPosition{Line: 2}, End: lspPosition{Line: 2}})
+	var titles []string
+	for _, a := range actions {
+		titles = append(titles, a.Title)
+		if len(a.Diagnostics) != 1 || a.Diagnostics[0] != diag {
+			t.Errorf("%s: diagnostics %+v, want the published one", a.Title, a.Diagnostics)
+		}
+		if edit := a.Edit.Changes[uri]; len(edit) != 1 || edit[0].Range.Start.Character != 22 {
+			t.Errorf("%s: edit %+v, want the version at column 22", a.Title, edit)
+		}
+	}
+	if want := []string{"Upgrade example.com/a to v1.2.0", "Upgrade example.com/a to v1.1.0"}; !equalStrings(titles, want) {
+		t.Errorf("actions = %q, want %q", titles, want)
+	}
+	if !actions[0].IsPreferred || actions[1].IsPreferred {
+		t.Errorf("only the latest version should be preferred: %+v", actions)
+	}
+}
+
+func TestLSPStaleResultDropped(t *testing.T) {
+	out := &syncBuffer{}
+	s := newLSPServer(strings.NewReader(""), out)
+	uri := "file:///tmp/stale/go.mod"
+	s.setDoc(uri, "bogus\n", 1)
+
+	s.mu.Lock()
+	stale := s.nextRun(uri).gen
+	s.nextRun(uri)
+	s.mu.Unlock()
+
+	s.publish(context.Background(), uri, stale)
+	if published := readPublished(t, out.String()); len(published) != 0 {
+		t.Errorf("stale pass published %+v", published)
+	}
+}
@@ -3,65 +3,294 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"golang.org/x/mod/modfile"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type ModuleInfo struct {
-	Path    string `json:"Path"`
-	Version string `json:"Version"`
-	Update  *struct {
-		Path    string `json:"Path"`
-		Version string `json:"Version"`
+	Path     string     `json:"Path"`
+	Version  string     `json:"Version"`
+	Main     bool       `json:"Main,omitempty"`
+	Indirect bool       `json:"Indirect,omitempty"`
+	Sum      string     `json:"Sum,omitempty"`
+	Dir      string     `json:"Dir,omitempty"`
+	Time     *time.Time `json:"Time,omitempty"`
+	Update   *struct {
+		Path    string     `json:"Path"`
+		Version string     `json:"Version"`
+		Time    *time.Time `json:"Time,omitempty"`
 	} `json:"Update,omitempty"`
+	Retracted  []string          `json:"Retracted,omitempty"`
+	Deprecated string            `json:"Deprecated,omitempty"`
+	Versions   []string          `json:"Versions,omitempty"`
+	Coverage   *CallSiteCoverage `json:"Coverage,omitempty"`
+	Churn      *DiffStat         `json:"Churn,omitempty"`
+	Pin        *Pin              `json:"Pin,omitempty"`
+	Quality    *ModuleQuality    `json:"Quality,omitempty"`
+	Priority   *UpdatePriority   `json:"Priority,omitempty"`
 }
 
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: go run main.go <git-repo-url>")
-		os.Exit(1)
+type options struct {
+	binSize          bool
+	binSizeThreshold float64
+	benchPkgs        string
+	bench            string
+	benchCount       int
+	benchAlpha       float64
+	coverage         bool
+	churn            bool
+	noticeText       string
+	noticeHTML       string
+	bazelDeps        string
+	bazelModule      string
+	bazelDrift       bool
+	goVersions       bool
+	pluginDir        string
+	pluginTimeout    time.Duration
+	failOn           string
+	template         string
+	format           string
+	priority         bool
+	priorityWeights  string
+	quality          bool
+	stdlib           bool
+}
+
+func analyzeCommand(fs *flag.FlagSet) func() error {
+	var opts options
+	fs.BoolVar(&opts.binSize, "binsize", false, "build main packages before and after each update and report binary size deltas")
+	fs.Float64Var(&opts.binSizeThreshold, "binsize-threshold", 0, "fail if any binary grows by more than this percentage (0 disables)")
+	fs.StringVar(&opts.benchPkgs, "bench-pkgs", "", "comma-separated packages whose benchmarks are compared before and after each update")
+	fs.StringVar(&opts.bench, "bench", ".", "benchmark regexp passed to go test -bench")
+	fs.IntVar(&opts.benchCount, "bench-count", 10, "number of benchmark runs per dependency set")
+	fs.Float64Var(&opts.benchAlpha, "bench-alpha", 0.05, "p-value below which a benchmark difference is significant")
+	fs.BoolVar(&opts.coverage, "coverage", false, "run the module's tests and report how many call sites into each dependency are covered")
+	fs.BoolVar(&opts.churn, "churn", false, "diff each dependency's current and update versions and report upstream churn")
+	fs.StringVar(&opts.noticeText, "notice", "", "write a third-party NOTICE text file for modules linked into main packages")
+	fs.StringVar(&opts.noticeHTML, "notice-html", "", "write the third-party notices as HTML")
+	fs.StringVar(&opts.bazelDeps, "bazel-deps", "", "write go_repository rules for the build list to this .bzl file")
+	fs.StringVar(&opts.bazelModule, "bazel-module", "", "write a MODULE.bazel go_deps fragment to this file")
+	fs.BoolVar(&opts.bazelDrift, "bazel-drift", false, "report drift between deps.bzl files in the repository and go.mod")
+	fs.BoolVar(&opts.goVersions, "go-versions", false, "check Go versions in Dockerfiles, CI workflows and version files against go.mod")
+	fs.StringVar(&opts.pluginDir, "plugin-dir", "", "directory of check plugin executables")
+	fs.DurationVar(&opts.pluginTimeout, "plugin-timeout", 30*time.Second, "maximum run time of each check plugin")
+	fs.StringVar(&opts.failOn, "fail-on", "", "exit with an error if any finding has at least this severity (info, warning or error)")
+	fs.StringVar(&opts.template, "template", "", "render the report with this text/template file instead of the built-in format")
+	fs.StringVar(&opts.format, "format", "text", "report format: text, github (Actions workflow commands) or gitlab (Code Quality JSON)")
+	fs.BoolVar(&opts.priority, "priority", false, "rank updates by a weighted score of vulnerabilities, libyear, semver class, directness, usage and, with -coverage, test coverage")
+	fs.StringVar(&opts.priorityWeights, "priority-weights", defaultPriorityWeights, "comma-separated signal=weight pairs overriding the default -priority weights")
+	fs.BoolVar(&opts.quality, "quality", false, "grade each dependency in the module cache on tests, license, vet, docs and go version")
+	fs.BoolVar(&opts.stdlib, "stdlib", false, "suggest standard library replacements for dependencies superseded by the go version")
+	return func() error { return runAnalyze(fs, opts) }
+}
+
+func runAnalyze(fs *flag.FlagSet, opts options) error {
+	if fs.NArg() != 1 {
+		return errUsage
 	}
 
-	repoURL := os.Args[1]
-	temDir, err := os.MkdirTemp("", "go-dep-analysis")
+	if opts.format != "text" && opts.format != "github" && opts.format != "gitlab" {
+		return fmt.Errorf("unknown report format %q", opts.format)
+	}
+	if opts.format != "text" && (opts.goVersions || opts.bazelDrift || opts.benchPkgs != "") {
+		fmt.Fprintf(os.Stderr, "warning: -go-versions, -bazel-drift and -bench-pkgs only report with -format=text\n")
+	}
+	if _, ok := severityRank[opts.failOn]; opts.failOn != "" && !ok {
+		return usageErrorf("unknown -fail-on severity %q; want info, warning or error", opts.failOn)
+	}
+	weights, err := parsePriorityWeights(opts.priorityWeights)
 	if err != nil {
-		log.Fatalf("Error creating temporary directory: %v", err)
+		return fmt.Errorf("error parsing priority weights: %v", err)
+	}
+	if opts.priority && !opts.coverage && weights["coverage"] != 0 {
+		fmt.Fprintf(os.Stderr, "warning: -priority without -coverage scores the coverage signal as 0; pass -coverage to measure it\n")
+	}
+	tmpl, err := loadReportTemplate(opts.template)
+	if err != nil {
+		return fmt.Errorf("error loading report template: %v", err)
 	}
-	defer func(path string) {
-		err := os.RemoveAll(path)
-		if err != nil {
-			log.Fatalf("Error removing temporary directory: %v", err)
-		}
-	}(temDir)
 
-	if err := cloneRepo(repoURL, temDir); err != nil {
-		log.Fatalf("Error cloning repository: %v", err)
+	repoURL := fs.Arg(0)
+	temDir, goModPath, err := checkoutRepo(repoURL)
+	if temDir != "" {
+		defer func(path string) {
+			if err := os.RemoveAll(path); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: error removing temporary directory: %v\n", err)
+			}
+		}(temDir)
+	}
+	if err != nil {
+		return fmt.Errorf("error preparing repository: %v", err)
 	}
 
-	goModPath, err := findGoMod(temDir)
+	moduleName, goVersion, toolchain, err := parseGoMod(goModPath)
 	if err != nil {
-		log.Fatalf("Error finding go.mod: %v", err)
+		return fmt.Errorf("error parsing go.mod: %v", err)
 	}
 
-	moduleName, goVersion, err := parseGoMod(goModPath)
+	modules, err := listModules(temDir, true)
 	if err != nil {
-		log.Fatalf("Error parsing go.mod: %v", err)
+		return fmt.Errorf("error getting dependencies: %v", err)
+	}
+	if opts.quality {
+		if err := analyzeModuleQuality(filepath.Dir(goModPath), modules); err != nil {
+			return fmt.Errorf("error assessing module quality: %v", err)
+		}
+	}
+	deps := outdatedModules(modules)
+	pins, err := readPins(goModPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	applyPins(deps, pins)
+
+	if opts.coverage {
+		coverage, err := analyzeCallSiteCoverage(filepath.Dir(goModPath))
+		if err != nil {
+			return fmt.Errorf("error measuring call site coverage: %v", err)
+		}
+		for i := range deps {
+			deps[i].Coverage = coverage[deps[i].Path]
+		}
+	}
+
+	if opts.churn {
+		upstreamChurn(deps)
 	}
 
-	deps, err := getDependencies(temDir)
+	report := &Report{Module: moduleName, GoVersion: goVersion, Toolchain: toolchain, Modules: modules, Updates: deps}
+	if opts.priority {
+		usage, err := dependencyUsage(filepath.Dir(goModPath))
+		if err != nil {
+			return fmt.Errorf("error counting dependency usage: %v", err)
+		}
+		report.Priorities = prioritizeUpdates(deps, usage, lookupVulnerabilities(deps), weights)
+		rankUpdates(deps, report.Priorities)
+	}
+	var graph []GraphEdge
+	if opts.stdlib {
+		if graph, err = getModuleGraph(filepath.Dir(goModPath)); err != nil {
+			return fmt.Errorf("error reading module graph: %v", err)
+		}
+		report.Replacements, err = suggestStdlibReplacements(filepath.Dir(goModPath), goVersion, modules, graph)
+		if err != nil {
+			return fmt.Errorf("error matching standard library replacements: %v", err)
+		}
+		report.Findings = append(report.Findings, stdlibFindings(report.Replacements)...)
+	}
+	plugins, err := discoverPlugins(opts.pluginDir)
 	if err != nil {
-		log.Fatalf("Error getting dependencies: %v", err)
+		return fmt.Errorf("error discovering plugins: %v", err)
+	}
+	if len(plugins) > 0 {
+		if graph == nil {
+			if graph, err = getModuleGraph(filepath.Dir(goModPath)); err != nil {
+				return fmt.Errorf("error reading module graph: %v", err)
+			}
+		}
+		report.Graph = graph
+		findings, err := runPlugins(plugins, report, opts.pluginTimeout)
+		if err != nil {
+			return fmt.Errorf("error running plugins: %v", err)
+		}
+		report.Findings = append(report.Findings, findings...)
 	}
 
-	printResults(moduleName, goVersion, deps)
+	sortFindings(report.Findings)
+	if err := writeReport(opts.format, tmpl, report, temDir, goModPath); err != nil {
+		return fmt.Errorf("error rendering report: %v", err)
+	}
+	if opts.format == "text" {
+		printPriorities(report.Priorities)
+		if opts.quality {
+			printModuleQuality(report.Modules)
+		}
+		printStdlibSuggestions(goVersion, report.Replacements)
+	}
+
+	if opts.noticeText != "" || opts.noticeHTML != "" {
+		if err := writeNotices(filepath.Dir(goModPath), moduleName, opts.noticeText, opts.noticeHTML); err != nil {
+			return fmt.Errorf("error generating notices: %v", err)
+		}
+	}
+
+	if opts.goVersions && opts.format == "text" {
+		pins, err := findGoVersionPins(temDir)
+		if err != nil {
+			return fmt.Errorf("error scanning Go versions: %v", err)
+		}
+		printGoVersionIssues(toolchain, checkGoVersionPins(goVersion, toolchain, pins))
+	}
+
+	if opts.bazelDeps != "" || opts.bazelModule != "" || opts.bazelDrift {
+		if err := runBazel(temDir, filepath.Dir(goModPath), opts); err != nil {
+			return fmt.Errorf("error generating Bazel output: %v", err)
+		}
+	}
+
+	if opts.binSize {
+		reports, err := analyzeBinarySizes(filepath.Dir(goModPath), deps)
+		if err != nil {
+			return fmt.Errorf("error analyzing binary sizes: %v", err)
+		}
+		if opts.format == "text" {
+			printBinarySizes(reports)
+		}
+		if violations := binarySizeViolations(reports, opts.binSizeThreshold); len(violations) > 0 {
+			return fmt.Errorf("binary size growth exceeds %.2f%%:\n%s", opts.binSizeThreshold, strings.Join(violations, "\n"))
+		}
+	}
+
+	if opts.benchPkgs != "" && opts.format == "text" {
+		reports, err := analyzeBenchmarks(filepath.Dir(goModPath), deps, strings.Split(opts.benchPkgs, ","), opts.bench, opts.benchCount, opts.benchAlpha)
+		if err != nil {
+			return fmt.Errorf("error running benchmarks: %v", err)
+		}
+		printBenchmarks(reports)
+	}
+
+	if violations := policyViolations(report.Findings, opts.failOn); len(violations) > 0 {
+		return fmt.Errorf("%d findings at or above severity %q", len(violations), opts.failOn)
+	}
+	return nil
+}
+
+func checkoutRepo(url string) (dir, goModPath string, err error) {
+	dir, err = os.MkdirTemp("", "go-dep-analysis")
+	if err != nil {
+		return "", "", fmt.Errorf("error creating temporary directory: %v", err)
+	}
+	sp := startSpan("clone", "repo.url", url)
+	err = cloneRepo(url, dir)
+	sp.End(err)
+	if err != nil {
+		return dir, "", fmt.Errorf("error cloning repository: %v", err)
+	}
+	sp = startSpan("discover modules", "repo.url", url)
+	goModPath, err = findGoMod(dir)
+	if err != nil {
+		goModPath, err = migrateLegacy(dir, url)
+	}
+	sp.End(err)
+	if err != nil {
+		return dir, "", fmt.Errorf("error finding go.mod: %v", err)
+	}
+	return dir, goModPath, nil
 }
 
 func cloneRepo(url, dir string) error {
-	cmd := exec.Command("git", "clone", url, dir)
+	args := []string{"clone", url, dir}
+	if globals.quiet {
+		args = []string{"clone", "--quiet", url, dir}
+	}
+	cmd := exec.Command("git", args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
@@ -91,25 +320,60 @@ func findGoMod(dir string) (string, error) {
 	return goModPath, nil
 }
 
-func parseGoMod(goModPath string) (modulePath, goVersion string, err error) {
+func parseGoMod(goModPath string) (modulePath, goVersion, toolchain string, err error) {
+	sp := startSpan("parse go.mod", "gomod.path", goModPath)
+	defer func() { sp.End(err) }()
 	data, err := os.ReadFile(goModPath)
 	if err != nil {
-		return "", "", fmt.Errorf("error reading go.mod: %v", err)
+		return "", "", "", fmt.Errorf("error reading go.mod: %v", err)
 	}
 	modFile, err := modfile.Parse(goModPath, data, nil)
 	if err != nil {
-		return "", "", fmt.Errorf("error parsing go.mod: %v", err)
+		return "", "", "", fmt.Errorf("error parsing go.mod: %v", err)
 	}
 
 	if modFile.Module == nil {
-		return "", "", fmt.Errorf("could not find go.mod")
+		return "", "", "", fmt.Errorf("could not find go.mod")
+	}
+	if modFile.Go != nil {
+		goVersion = modFile.Go.Version
+	}
+	if modFile.Toolchain != nil {
+		toolchain = modFile.Toolchain.Name
 	}
 
-	return modFile.Module.Mod.Path, modFile.Go.Version, nil
+	return modFile.Module.Mod.Path, goVersion, toolchain, nil
 }
 
 func getDependencies(dir string) ([]ModuleInfo, error) {
-	cmd := exec.Command("go", "list", "-m", "-u", "-json", "all")
+	modules, err := listModules(dir, true)
+	if err != nil {
+		return nil, err
+	}
+	return outdatedModules(modules), nil
+}
+
+func outdatedModules(modules []ModuleInfo) []ModuleInfo {
+	var deps []ModuleInfo
+	for _, m := range modules {
+		if m.Update != nil {
+			deps = append(deps, m)
+		}
+	}
+	return deps
+}
+
+func listModules(dir string, update bool) (modules []ModuleInfo, err error) {
+	args := []string{"list", "-m", "-json", "all"}
+	if update {
+		args = []string{"list", "-m", "-u", "-retracted", "-json", "all"}
+	}
+	sp := startSpan("go list", "go.args", strings.Join(args, " "))
+	defer func() {
+		sp.SetAttributes("module.count", strconv.Itoa(len(modules)))
+		sp.End(err)
+	}()
+	cmd := exec.Command("go", args...)
 	cmd.Dir = dir
 	var out bytes.Buffer
 	cmd.Stdout = &out
@@ -119,31 +383,44 @@ func getDependencies(dir string) ([]ModuleInfo, error) {
 		return nil, err
 	}
 
-	var deps []ModuleInfo
 	dec := json.NewDecoder(&out)
 	for dec.More() {
 		var m ModuleInfo
 		if err := dec.Decode(&m); err != nil {
 			return nil, err
 		}
-		if m.Update != nil {
-			deps = append(deps, m)
-		}
+		modules = append(modules, m)
 	}
-	return deps, nil
+	return modules, nil
 }
 
-func printResults(moduleName, goVersion string, deps []ModuleInfo) {
-	fmt.Printf("Module: %s\n", moduleName)
-	fmt.Printf("Go Module Version: %s\n", goVersion)
-	if len(deps) > 0 {
-		fmt.Println("Dependencies that can be updated:")
-		for _, dep := range deps {
-			if dep.Update != nil {
-				fmt.Printf("- %s: %s -> %s\n", dep.Path, dep.Version, dep.Update.Version)
-			}
+func updateAnnotations(dep ModuleInfo) string {
+	var notes []string
+	if dep.Pin != nil {
+		if dep.Pin.Active(time.Now()) {
+			notes = append(notes, dep.Pin.String())
+		} else {
+			notes = append(notes, "pin expired on "+dep.Pin.Until)
 		}
-	} else {
-		fmt.Println("All dependencies are up to date.")
 	}
+	if len(dep.Retracted) > 0 {
+		notes = append(notes, "retracted: "+strings.Join(dep.Retracted, "; "))
+	}
+	if dep.Deprecated != "" {
+		notes = append(notes, "deprecated: "+dep.Deprecated)
+	}
+	if dep.Coverage != nil {
+		notes = append(notes, fmt.Sprintf("call sites covered by tests: %d/%d (%.1f%%)",
+			dep.Coverage.Covered, dep.Coverage.Sites, dep.Coverage.Percent()))
+	}
+	if dep.Priority != nil {
+		notes = append(notes, fmt.Sprintf("priority score %.2f", dep.Priority.Score))
+	}
+	if dep.Churn != nil {
+		notes = append(notes, fmt.Sprintf("upstream churn: %d files, +%d -%d", dep.Churn.Files, dep.Churn.Added, dep.Churn.Removed))
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(notes, ", ") + "]"
 }
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/version"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// mergeDriverCommand is meant to be registered as a git merge driver:
+//
+//	git config merge.gomod.driver "depanalysis merge-driver %O %A %B %P"
+//	echo 'go.mod merge=gomod' >> .gitattributes
+//	echo 'go.sum merge=gomod' >> .gitattributes
+//
+// The merged file replaces ours. Genuine conflicts are reported on stderr
+// and make the driver exit with status 1, leaving ours' side in place.
+func mergeDriverCommand(fs *flag.FlagSet) func() error {
+	kind := fs.String("kind", "", "file kind, go.mod or go.sum; detected from the path by default")
+	return func() error { return runMergeDriver(fs, *kind) }
+}
+
+func runMergeDriver(fs *flag.FlagSet, kind string) error {
+	if fs.NArg() != 3 && fs.NArg() != 4 {
+		return errUsage
+	}
+	base, ours, theirs := fs.Arg(0), fs.Arg(1), fs.Arg(2)
+	name := ours
+	if fs.NArg() == 4 {
+		name = fs.Arg(3)
+	}
+	if kind == "" {
+		kind = filepath.Base(name)
+	}
+
+	var data [3][]byte
+	for i, path := range []string{base, ours, theirs} {
+		var err error
+		if data[i], err = os.ReadFile(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error reading %s: %v", path, err)
+		}
+	}
+	baseData, oursData, theirsData := data[0], data[1], data[2]
+
+	var merged []byte
+	var conflicts []string
+	var err error
+	switch kind {
+	case "go.mod":
+		merged, conflicts, err = mergeGoMod(baseData, oursData, theirsData)
+	case "go.sum":
+		merged, conflicts = mergeGoSum(baseData, oursData, theirsData)
+	default:
+		return fmt.Errorf("cannot merge %s: use -kind go.mod or -kind go.sum", name)
+	}
+	if err != nil {
+		return fmt.Errorf("error merging %s: %v", name, err)
+	}
+	if err := os.WriteFile(ours, merged, 0o644); err != nil {
+		return fmt.Errorf("error writing %s: %v", ours, err)
+	}
+	if len(conflicts) > 0 {
+		fmt.Fprintf(os.Stderr, "%s: %d conflicts could not be merged, keeping our side:\n", name, len(conflicts))
+		for _, c := range conflicts {
+			fmt.Fprintf(os.Stderr, "- %s\n", c)
+		}
+		return exitStatus(1)
+	}
+	return nil
+}
+
+// mergeGoMod merges two go.mod files that diverged from base. Requirements
+// take the higher of the two versions, as minimal version selection would;
+// the go and toolchain lines take the newer release. Every other directive
+// is merged three-way and conflicts when both sides changed it differently.
+// The result is built on ours so that its comments and layout survive.
+func mergeGoMod(baseData, oursData, theirsData []byte) ([]byte, []string, error) {
+	base, err := modfile.Parse("base/go.mod", baseData, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	ours, err := modfile.Parse("ours/go.mod", oursData, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	theirs, err := modfile.Parse("theirs/go.mod", theirsData, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var conflicts []string
+	conflict := func(format string, args ...any) {
+		conflicts = append(conflicts, fmt.Sprintf(format, args...))
+	}
+
+	baseModule, oursModule, theirsModule := "", "", ""
+	if base.Module != nil {
+		baseModule = base.Module.Mod.Path
+	}
+	if ours.Module != nil {
+		oursModule = ours.Module.Mod.Path
+	}
+	if theirs.Module != nil {
+		theirsModule = theirs.Module.Mod.Path
+	}
+	switch merge3(baseModule, oursModule, theirsModule) {
+	case mergeTheirs:
+		if err := ours.AddModuleStmt(theirsModule); err != nil {
+			return nil, nil, err
+		}
+	case mergeConflict:
+		conflict("module: ours %s, theirs %s (base %s)", oursModule, theirsModule, orNone(baseModule))
+	}
+
+	if theirs.Go != nil && (ours.Go == nil || version.Compare("go"+theirs.Go.Version, "go"+ours.Go.Version) > 0) {
+		if err := ours.AddGoStmt(theirs.Go.Version); err != nil {
+			return nil, nil, err
+		}
+	}
+	if theirs.Toolchain != nil && (ours.Toolchain == nil || version.Compare(theirs.Toolchain.Name, ours.Toolchain.Name) > 0) {
+		if err := ours.AddToolchainStmt(theirs.Toolchain.Name); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	conflicts = append(conflicts, mergeRequires(ours, base.Require, theirs.Require)...)
+
+	replaceKey := func(r *modfile.Replace) string { return r.Old.String() }
+	replaceValue := func(r *modfile.Replace) string { return r.New.String() }
+	baseReplace := indexDirectives(base.Replace, replaceKey, replaceValue)
+	theirsReplace := indexDirectives(theirs.Replace, replaceKey, replaceValue)
+	oursReplace := indexDirectives(ours.Replace, replaceKey, replaceValue)
+	for _, key := range unionKeys(baseReplace, oursReplace, theirsReplace) {
+		b, o, t := baseReplace[key], oursReplace[key], theirsReplace[key]
+		switch merge3(b.value, o.value, t.value) {
+		case mergeTheirs:
+			if t.value == "" {
+				err = ours.DropReplace(o.directive.Old.Path, o.directive.Old.Version)
+			} else {
+				r := t.directive
+				err = ours.AddReplace(r.Old.Path, r.Old.Version, r.New.Path, r.New.Version)
+			}
+		case mergeConflict:
+			conflict("replace %s: ours %s, theirs %s (base %s)", key, orNone(o.value), orNone(t.value), orNone(b.value))
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	excludeKey := func(e *modfile.Exclude) string { return e.Mod.String() }
+	baseExclude := indexDirectives(base.Exclude, excludeKey, excludeKey)
+	theirsExclude := indexDirectives(theirs.Exclude, excludeKey, excludeKey)
+	oursExclude := indexDirectives(ours.Exclude, excludeKey, excludeKey)
+	for _, key := range unionKeys(baseExclude, oursExclude, theirsExclude) {
+		b, o, t := baseExclude[key], oursExclude[key], theirsExclude[key]
+		if merge3(b.value, o.value, t.value) != mergeTheirs {
+			continue
+		}
+		if t.value == "" {
+			err = ours.DropExclude(o.directive.Mod.Path, o.directive.Mod.Version)
+		} else {
+			err = ours.AddExclude(t.directive.Mod.Path, t.directive.Mod.Version)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	retractKey := func(r *modfile.Retract) string { return "[" + r.Low + ", " + r.High + "]" }
+	retractValue := func(r *modfile.Retract) string { return retractKey(r) + " " + r.Rationale }
+	baseRetract := indexDirectives(base.Retract, retractKey, retractValue)
+	theirsRetract := indexDirectives(theirs.Retract, retractKey, retractValue)
+	oursRetract := indexDirectives(ours.Retract, retractKey, retractValue)
+	for _, key := range unionKeys(baseRetract, oursRetract, theirsRetract) {
+		b, o, t := baseRetract[key], oursRetract[key], theirsRetract[key]
+		switch merge3(b.value, o.value, t.value) {
+		case mergeTheirs:
+			if o.value != "" {
+				err = ours.DropRetract(o.directive.VersionInterval)
+			}
+			if err == nil && t.value != "" {
+				err = ours.AddRetract(t.directive.VersionInterval, t.directive.Rationale)
+			}
+		case mergeConflict:
+			conflict("retract %s: ours %q, theirs %q (base %q)", key, o.value, t.value, b.value)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	ours.Cleanup()
+	data, err := ours.Format()
+	return data, conflicts, err
+}
+
+// mergeRequires raises the requirements of ours to the versions required by
+// theirs. A requirement dropped on one side stays dropped unless the other
+// side changed its version; that case is a conflict.
+func mergeRequires(ours *modfile.File, base, theirs []*modfile.Require) []string {
+	byPath := func(reqs []*modfile.Require) map[string]*modfile.Require {
+		m := make(map[string]*modfile.Require, len(reqs))
+		for _, r := range reqs {
+			m[r.Mod.Path] = r
+		}
+		return m
+	}
+	baseReq, oursReq, theirsReq := byPath(base), byPath(ours.Require), byPath(theirs)
+
+	var conflicts []string
+	var merged []*modfile.Require
+	var added []*modfile.Require
+	for _, r := range ours.Require {
+		b, t := baseReq[r.Mod.Path], theirsReq[r.Mod.Path]
+		switch {
+		case t == nil && b != nil && b.Mod.Version == r.Mod.Version:
+			continue
+		case t == nil && b != nil:
+			conflicts = append(conflicts, fmt.Sprintf("require %s: ours %s, theirs dropped it (base %s)", r.Mod.Path, r.Mod.Version, b.Mod.Version))
+		}
+		m := &modfile.Require{Mod: r.Mod, Indirect: r.Indirect}
+		if t != nil {
+			switch c := semver.Compare(t.Mod.Version, r.Mod.Version); {
+			case c > 0:
+				m.Mod, m.Indirect = t.Mod, t.Indirect
+			case c == 0:
+				m.Indirect = r.Indirect && t.Indirect
+			}
+		}
+		merged = append(merged, m)
+	}
+	for _, t := range theirs {
+		if oursReq[t.Mod.Path] != nil {
+			continue
+		}
+		b := baseReq[t.Mod.Path]
+		switch {
+		case b != nil && b.Mod.Version == t.Mod.Version:
+			continue
+		case b != nil:
+			conflicts = append(conflicts, fmt.Sprintf("require %s: ours dropped it, theirs %s (base %s)", t.Mod.Path, t.Mod.Version, b.Mod.Version))
+			continue
+		}
+		merged = append(merged, &modfile.Require{Mod: t.Mod, Indirect: t.Indirect})
+		added = append(added, t)
+	}
+	ours.SetRequireSeparateIndirect(merged)
+
+	// Carry over the comments of requirements that only theirs has, such
+	// as pin annotations.
+	for _, t := range added {
+		for _, r := range ours.Require {
+			if r.Mod.Path == t.Mod.Path {
+				r.Syntax.Before = t.Syntax.Before
+				if r.Syntax.Suffix == nil && !t.Indirect {
+					r.Syntax.Suffix = t.Syntax.Suffix
+				}
+			}
+		}
+	}
+	return conflicts
+}
+
+type mergeOutcome int
+
+const (
+	mergeOurs mergeOutcome = iota
+	mergeTheirs
+	mergeConflict
+)
+
+// merge3 decides a single value of a three-way merge, where the empty
+// string stands for an absent directive.
+func merge3(base, ours, theirs string) mergeOutcome {
+	switch {
+	case ours == theirs || theirs == base:
+		return mergeOurs
+	case ours == base:
+		return mergeTheirs
+	}
+	return mergeConflict
+}
+
+type indexedDirective[T any] struct {
+	directive T
+	value     string
+}
+
+func indexDirectives[T any](directives []T, key, value func(T) string) map[string]indexedDirective[T] {
+	m := make(map[string]indexedDirective[T], len(directives))
+	for _, d := range directives {
+		m[key(d)] = indexedDirective[T]{d, value(d)}
+	}
+	return m
+}
+
+func unionKeys[V any](maps ...map[string]V) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// mergeGoSum merges go.sum files three-way, one module version at a time:
+// lines added on either side are kept, and lines removed on one side, as
+// go mod tidy does, stay removed unless the other side changed them. Two
+// different hashes for the same module version are a conflict; ours is
+// kept. The result is sorted in the order the go command writes it.
+func mergeGoSum(baseData, oursData, theirsData []byte) ([]byte, []string) {
+	type key struct{ path, version string }
+	parse := func(data []byte) map[key]string {
+		hashes := make(map[key]string)
+		for _, line := range strings.Split(string(data), "\n") {
+			if fields := strings.Fields(line); len(fields) == 3 {
+				hashes[key{fields[0], fields[1]}] = fields[2]
+			}
+		}
+		return hashes
+	}
+	base, ours, theirs := parse(baseData), parse(oursData), parse(theirsData)
+
+	merged := make(map[key]string)
+	var keys []key
+	var conflicts []string
+	for _, side := range []map[key]string{ours, theirs} {
+		for k := range side {
+			if _, done := merged[k]; done {
+				continue
+			}
+			h := ours[k]
+			switch merge3(base[k], ours[k], theirs[k]) {
+			case mergeTheirs:
+				h = theirs[k]
+			case mergeConflict:
+				c := fmt.Sprintf("%s %s: ours %s, theirs %s", k.path, k.version, orNone(ours[k]), orNone(theirs[k]))
+				if base[k] != "" {
+					c += fmt.Sprintf(" (base %s)", base[k])
+				}
+				conflicts = append(conflicts, c)
+			}
+			merged[k] = h
+			if h != "" {
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].path != keys[j].path {
+			return keys[i].path < keys[j].path
+		}
+		return semverLess(keys[i].version, keys[j].version)
+	})
+	sort.Strings(conflicts)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s %s %s\n", k.path, k.version, merged[k])
+	}
+	return []byte(b.String()), conflicts
+}
+
+// semverLess orders go.sum versions, placing the /go.mod hash of a version
+// right after its module hash.
+func semverLess(a, b string) bool {
+	av, aMod := strings.CutSuffix(a, "/go.mod")
+	bv, bMod := strings.CutSuffix(b, "/go.mod")
+	if c := semver.Compare(av, bv); c != 0 {
+		return c < 0
+	}
+	if av != bv {
+		return av < bv
+	}
+	return !aMod && bMod
+}
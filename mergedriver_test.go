@@ -0,0 +1,305 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const mergeBaseGoMod = `module example.com/m
+
+go 1.21
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.0.0
+)
+
+require example.com/c v1.0.0 // indirect
+
+replace example.com/r => ../r
+`
+
+func TestMergeGoMod(t *testing.T) {
+	tests := []struct {
+		name          string
+		ours, theirs  string
+		want          string
+		wantConflicts []string
+	}{
+		{
+			name: "requirements take the higher version and go takes the newer release",
+			ours: strings.NewReplacer("example.com/a v1.0.0", "example.com/a v1.2.0", "go 1.21", "go 1.22").Replace(mergeBaseGoMod),
+			theirs: strings.NewReplacer("example.com/a v1.0.0", "example.com/a v1.1.0", "example.com/b v1.0.0", "example.com/b v1.3.0",
+				"go 1.21", "go 1.21\n\ntoolchain go1.23.1").Replace(mergeBaseGoMod),
+			want: `module example.com/m
+
+go 1.22
+
+toolchain go1.23.1
+
+require (
+	example.com/a v1.2.0
+	example.com/b v1.3.0
+)
+
+require example.com/c v1.0.0 // indirect
+
+replace example.com/r => ../r
+`,
+		},
+		{
+			name: "requirements added on both sides are kept with their comments",
+			ours: strings.Replace(mergeBaseGoMod, "example.com/b v1.0.0\n", "example.com/b v1.0.0\n\texample.com/d v1.0.0\n", 1),
+			theirs: strings.Replace(mergeBaseGoMod, "example.com/b v1.0.0\n",
+				"example.com/b v1.0.0\n\t// pin: waiting on upstream fix\n\texample.com/e v0.5.0\n", 1),
+			want: `module example.com/m
+
+go 1.21
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.0.0
+	example.com/d v1.0.0
+	// pin: waiting on upstream fix
+	example.com/e v0.5.0
+)
+
+require example.com/c v1.0.0 // indirect
+
+replace example.com/r => ../r
+`,
+		},
+		{
+			name:   "a dependency dropped on one side stays dropped",
+			ours:   strings.Replace(mergeBaseGoMod, "\texample.com/b v1.0.0\n", "", 1),
+			theirs: strings.Replace(mergeBaseGoMod, "example.com/a v1.0.0", "example.com/a v1.0.1", 1),
+			want: `module example.com/m
+
+go 1.21
+
+require example.com/a v1.0.1
+
+require example.com/c v1.0.0 // indirect
+
+replace example.com/r => ../r
+`,
+		},
+		{
+			name:   "a dependency dropped on one side and upgraded on the other conflicts",
+			ours:   strings.Replace(mergeBaseGoMod, "example.com/b v1.0.0", "example.com/b v1.1.0", 1),
+			theirs: strings.Replace(mergeBaseGoMod, "\texample.com/b v1.0.0\n", "", 1),
+			want: `module example.com/m
+
+go 1.21
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.1.0
+)
+
+require example.com/c v1.0.0 // indirect
+
+replace example.com/r => ../r
+`,
+			wantConflicts: []string{"require example.com/b: ours v1.1.0, theirs dropped it (base v1.0.0)"},
+		},
+		{
+			name: "an indirect requirement made direct on either side becomes direct",
+			ours: mergeBaseGoMod,
+			theirs: strings.Replace(strings.Replace(mergeBaseGoMod, "\nrequire example.com/c v1.0.0 // indirect\n", "", 1),
+				"example.com/b v1.0.0\n", "example.com/b v1.0.0\n\texample.com/c v1.0.0\n", 1),
+			want: `module example.com/m
+
+go 1.21
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.0.0
+)
+
+require example.com/c v1.0.0
+
+replace example.com/r => ../r
+`,
+		},
+		{
+			name:   "replacements merge three-way",
+			ours:   mergeBaseGoMod + "\nreplace example.com/s => ../s\n",
+			theirs: strings.Replace(mergeBaseGoMod, "\nreplace example.com/r => ../r\n", "", 1) + "\nexclude example.com/a v0.9.0\n",
+			want: `module example.com/m
+
+go 1.21
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.0.0
+)
+
+require example.com/c v1.0.0 // indirect
+
+replace example.com/s => ../s
+
+exclude example.com/a v0.9.0
+`,
+		},
+		{
+			name:   "a replacement changed differently on both sides conflicts",
+			ours:   strings.Replace(mergeBaseGoMod, "../r", "../r2", 1),
+			theirs: strings.Replace(mergeBaseGoMod, "../r", "example.com/fork v1.0.0", 1),
+			want:   strings.Replace(mergeBaseGoMod, "../r", "../r2", 1),
+			wantConflicts: []string{
+				"replace example.com/r: ours ../r2, theirs example.com/fork@v1.0.0 (base ../r)",
+			},
+		},
+		{
+			name:          "a renamed module conflicts when both sides rename it",
+			ours:          strings.Replace(mergeBaseGoMod, "module example.com/m", "module example.com/m/v2", 1),
+			theirs:        strings.Replace(mergeBaseGoMod, "module example.com/m", "module example.com/n", 1),
+			want:          strings.Replace(mergeBaseGoMod, "module example.com/m", "module example.com/m/v2", 1),
+			wantConflicts: []string{"module: ours example.com/m/v2, theirs example.com/n (base example.com/m)"},
+		},
+	}
+	for _, tt := range tests {
+		got, conflicts, err := mergeGoMod([]byte(mergeBaseGoMod), []byte(tt.ours), []byte(tt.theirs))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: merged go.mod =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+		if !reflect.DeepEqual(conflicts, tt.wantConflicts) {
+			t.Errorf("%s: conflicts = %q, want %q", tt.name, conflicts, tt.wantConflicts)
+		}
+	}
+}
+
+func TestMergeGoModParseError(t *testing.T) {
+	if _, _, err := mergeGoMod([]byte(mergeBaseGoMod), []byte("module\n"), []byte(mergeBaseGoMod)); err == nil {
+		t.Error("merging an invalid go.mod succeeded")
+	}
+}
+
+func TestMergeGoSum(t *testing.T) {
+	const base = `example.com/a v1.0.0 h1:a1=
+example.com/a v1.0.0/go.mod h1:a1mod=
+example.com/b v1.0.0/go.mod h1:b1mod=
+`
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		wantConflicts      []string
+	}{
+		{
+			name: "lines added on both sides are merged in go command order",
+			ours: `example.com/a v1.10.0 h1:a10=
+example.com/a v1.10.0/go.mod h1:a10mod=
+example.com/b v1.0.0/go.mod h1:b1mod=
+`,
+			theirs: `example.com/a v1.2.0 h1:a2=
+example.com/a v1.2.0/go.mod h1:a2mod=
+example.com/a v1.10.0/go.mod h1:a10mod=
+example.com/b v1.0.0/go.mod h1:other=
+example.com/0 v0.1.0/go.mod h1:zero=
+`,
+			want: `example.com/0 v0.1.0/go.mod h1:zero=
+example.com/a v1.2.0 h1:a2=
+example.com/a v1.2.0/go.mod h1:a2mod=
+example.com/a v1.10.0 h1:a10=
+example.com/a v1.10.0/go.mod h1:a10mod=
+example.com/b v1.0.0/go.mod h1:b1mod=
+`,
+			wantConflicts: []string{"example.com/b v1.0.0/go.mod: ours h1:b1mod=, theirs h1:other="},
+		},
+		{
+			name:   "lines removed on one side stay removed",
+			base:   base,
+			ours:   "example.com/a v1.0.0/go.mod h1:a1mod=\n",
+			theirs: base + "example.com/c v1.0.0/go.mod h1:c1mod=\n",
+			want:   "example.com/a v1.0.0/go.mod h1:a1mod=\nexample.com/c v1.0.0/go.mod h1:c1mod=\n",
+		},
+		{
+			name:   "lines removed on both sides stay removed",
+			base:   base,
+			ours:   "example.com/a v1.0.0/go.mod h1:a1mod=\n",
+			theirs: "example.com/a v1.0.0/go.mod h1:a1mod=\nexample.com/b v1.0.0/go.mod h1:b1mod=\n",
+			want:   "example.com/a v1.0.0/go.mod h1:a1mod=\n",
+		},
+		{
+			name:          "a line removed on one side and changed on the other conflicts",
+			base:          base,
+			ours:          strings.Replace(base, "h1:b1mod=", "h1:changed=", 1),
+			theirs:        strings.Replace(base, "example.com/b v1.0.0/go.mod h1:b1mod=\n", "", 1),
+			want:          strings.Replace(base, "h1:b1mod=", "h1:changed=", 1),
+			wantConflicts: []string{"example.com/b v1.0.0/go.mod: ours h1:changed=, theirs none (base h1:b1mod=)"},
+		},
+	}
+	for _, tt := range tests {
+		got, conflicts := mergeGoSum([]byte(tt.base), []byte(tt.ours), []byte(tt.theirs))
+		if string(got) != tt.want {
+			t.Errorf("%s: merged go.sum =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+		if !reflect.DeepEqual(conflicts, tt.wantConflicts) {
+			t.Errorf("%s: conflicts = %q, want %q", tt.name, conflicts, tt.wantConflicts)
+		}
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		base, ours, theirs string
+		want               mergeOutcome
+	}{
+		{"a", "a", "a", mergeOurs},
+		{"a", "b", "a", mergeOurs},
+		{"a", "a", "b", mergeTheirs},
+		{"a", "b", "b", mergeOurs},
+		{"a", "b", "c", mergeConflict},
+		{"", "", "b", mergeTheirs},
+		{"a", "a", "", mergeTheirs},
+		{"a", "b", "", mergeConflict},
+	}
+	for _, tt := range tests {
+		if got := merge3(tt.base, tt.ours, tt.theirs); got != tt.want {
+			t.Errorf("merge3(%q, %q, %q) = %d, want %d", tt.base, tt.ours, tt.theirs, got, tt.want)
+		}
+	}
+}
+
+func TestRunMergeDriver(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base":     "example.com/a v1.0.0/go.mod h1:a=\n",
+		"ours":     "example.com/a v1.0.0/go.mod h1:a=\n",
+		"theirs":   "example.com/a v1.0.0/go.mod h1:a=\nexample.com/b v1.0.0/go.mod h1:b=\n",
+		"conflict": "example.com/b v1.0.0/go.mod h1:changed=\n",
+	})
+	run := func(args ...string) error {
+		fs, run := newCommandFlags(lookupCommand("merge-driver"))
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return run()
+	}
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	if err := run(path("base"), path("ours"), path("theirs"), "go.sum"); err != nil {
+		t.Fatalf("clean merge: %v", err)
+	}
+	if data, _ := os.ReadFile(path("ours")); string(data) != "example.com/a v1.0.0/go.mod h1:a=\nexample.com/b v1.0.0/go.mod h1:b=\n" {
+		t.Errorf("merged go.sum =\n%s", data)
+	}
+	if err := run(path("base"), path("ours"), path("conflict"), "sub/go.sum"); err != exitStatus(1) {
+		t.Errorf("conflicting merge returned %v, want exit status 1", err)
+	}
+	if err := run(path("base"), path("ours"), path("theirs"), "README"); err == nil || err == exitStatus(1) {
+		t.Errorf("merging an unknown file kind returned %v", err)
+	}
+	if err := run("-kind", "go.sum", path("base"), path("ours"), path("theirs")); err != nil {
+		t.Errorf("merge with -kind: %v", err)
+	}
+}
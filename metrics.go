@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const defaultCriticalModules = 10
+
+type ModuleMetrics struct {
+	Path        string  `json:"Path"`
+	Depth       int     `json:"Depth"`
+	FanIn       int     `json:"FanIn"`
+	FanOut      int     `json:"FanOut"`
+	Betweenness float64 `json:"Betweenness"`
+	Dominated   int     `json:"Dominated"`
+}
+
+type GraphMetrics struct {
+	Modules  int             `json:"Modules"`
+	Edges    int             `json:"Edges"`
+	MaxDepth int             `json:"MaxDepth"`
+	Metrics  []ModuleMetrics `json:"Metrics"`
+	Critical []ModuleMetrics `json:"Critical"`
+}
+
+type pathGraph struct {
+	nodes []string
+	index map[string]int
+	out   [][]int
+}
+
+// buildPathGraph collapses the requirement graph to one node per module
+// path, keeping only the requirements of the selected version of each
+// module. The go and toolchain pseudo-modules are dropped. Since go 1.17
+// the main module lists every indirect dependency itself; those edges are
+// only kept for modules that are not reachable through a real requirer.
+func buildPathGraph(edges []GraphEdge, mainModule string, modules []ModuleInfo) *pathGraph {
+	selected := make(map[string]string, len(modules))
+	indirect := make(map[string]bool)
+	for _, m := range modules {
+		selected[m.Path] = m.Version
+		indirect[m.Path] = m.Indirect
+	}
+
+	g := &pathGraph{index: make(map[string]int)}
+	node := func(path string) int {
+		i, ok := g.index[path]
+		if !ok {
+			i = len(g.nodes)
+			g.index[path] = i
+			g.nodes = append(g.nodes, path)
+			g.out = append(g.out, nil)
+		}
+		return i
+	}
+	root := node(mainModule)
+	seen := make(map[[2]int]bool)
+	var deferred []int
+	for _, e := range edges {
+		fromPath, fromVersion, _ := strings.Cut(e.From, "@")
+		toPath, _, _ := strings.Cut(e.To, "@")
+		if toPath == "go" || toPath == "toolchain" || fromPath == "go" || fromPath == "toolchain" {
+			continue
+		}
+		if fromPath != mainModule && selected[fromPath] != fromVersion {
+			continue
+		}
+		from, to := node(fromPath), node(toPath)
+		if from == root && indirect[toPath] {
+			deferred = append(deferred, to)
+			continue
+		}
+		if from != to && !seen[[2]int{from, to}] {
+			seen[[2]int{from, to}] = true
+			g.out[from] = append(g.out[from], to)
+		}
+	}
+	dist := g.distances(root, -1)
+	for _, to := range deferred {
+		if dist[to] < 0 && !seen[[2]int{root, to}] {
+			seen[[2]int{root, to}] = true
+			g.out[root] = append(g.out[root], to)
+		}
+	}
+	return g
+}
+
+// distances runs a breadth-first search from src, ignoring node skip, and
+// returns the distance to every node, or -1 where unreachable.
+func (g *pathGraph) distances(src, skip int) []int {
+	dist := make([]int, len(g.nodes))
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[src] = 0
+	queue := []int{src}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range g.out[v] {
+			if w != skip && dist[w] < 0 {
+				dist[w] = dist[v] + 1
+				queue = append(queue, w)
+			}
+		}
+	}
+	return dist
+}
+
+// betweenness computes normalized betweenness centrality with Brandes'
+// algorithm for unweighted directed graphs.
+func (g *pathGraph) betweenness() []float64 {
+	n := len(g.nodes)
+	cb := make([]float64, n)
+	for s := 0; s < n; s++ {
+		var stack []int
+		pred := make([][]int, n)
+		sigma := make([]float64, n)
+		dist := make([]int, n)
+		for i := range dist {
+			dist[i] = -1
+		}
+		sigma[s], dist[s] = 1, 0
+		queue := []int{s}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			stack = append(stack, v)
+			for _, w := range g.out[v] {
+				if dist[w] < 0 {
+					dist[w] = dist[v] + 1
+					queue = append(queue, w)
+				}
+				if dist[w] == dist[v]+1 {
+					sigma[w] += sigma[v]
+					pred[w] = append(pred[w], v)
+				}
+			}
+		}
+		delta := make([]float64, n)
+		for i := len(stack) - 1; i >= 0; i-- {
+			w := stack[i]
+			for _, v := range pred[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			if w != s {
+				cb[w] += delta[w]
+			}
+		}
+	}
+	if n > 2 {
+		for i := range cb {
+			cb[i] /= float64((n - 1) * (n - 2))
+		}
+	}
+	return cb
+}
+
+func computeGraphMetrics(edges []GraphEdge, mainModule string, modules []ModuleInfo, top int) *GraphMetrics {
+	g := buildPathGraph(edges, mainModule, modules)
+	root := g.index[mainModule]
+	depth := g.distances(root, -1)
+	between := g.betweenness()
+	fanIn := make([]int, len(g.nodes))
+	result := &GraphMetrics{}
+	for _, targets := range g.out {
+		result.Edges += len(targets)
+		for _, w := range targets {
+			fanIn[w]++
+		}
+	}
+
+	for i, path := range g.nodes {
+		if i == root || depth[i] < 0 {
+			continue
+		}
+		m := ModuleMetrics{Path: path, Depth: depth[i], FanIn: fanIn[i], FanOut: len(g.out[i]), Betweenness: between[i]}
+		without := g.distances(root, i)
+		for j, d := range without {
+			if j != i && d < 0 && depth[j] >= 0 {
+				m.Dominated++
+			}
+		}
+		result.Metrics = append(result.Metrics, m)
+		result.MaxDepth = max(result.MaxDepth, m.Depth)
+	}
+	result.Modules = len(result.Metrics)
+	sort.Slice(result.Metrics, func(i, j int) bool {
+		a, b := result.Metrics[i], result.Metrics[j]
+		if a.Depth != b.Depth {
+			return a.Depth < b.Depth
+		}
+		return a.Path < b.Path
+	})
+	result.Critical = criticalModules(result.Metrics, top)
+	return result
+}
+
+// criticalModules ranks modules by how many others would drop out of the
+// build list without them, breaking ties by betweenness and fan-in.
+func criticalModules(metrics []ModuleMetrics, top int) []ModuleMetrics {
+	var critical []ModuleMetrics
+	for _, m := range metrics {
+		if m.Dominated > 0 || m.Betweenness > 0 {
+			critical = append(critical, m)
+		}
+	}
+	sort.SliceStable(critical, func(i, j int) bool {
+		a, b := critical[i], critical[j]
+		if a.Dominated != b.Dominated {
+			return a.Dominated > b.Dominated
+		}
+		if a.Betweenness != b.Betweenness {
+			return a.Betweenness > b.Betweenness
+		}
+		return a.FanIn > b.FanIn
+	})
+	if len(critical) > top {
+		critical = critical[:top]
+	}
+	return critical
+}
+
+func printGraphMetrics(m *GraphMetrics) {
+	fmt.Printf("Module graph: %d modules, %d requirements, max depth %d\n", m.Modules, m.Edges, m.MaxDepth)
+	if len(m.Critical) > 0 {
+		fmt.Println("Critical modules:")
+		for _, c := range m.Critical {
+			fmt.Printf("- %s: %d modules depend only through it, betweenness %.3f, fan-in %d, fan-out %d, depth %d\n",
+				c.Path, c.Dominated, c.Betweenness, c.FanIn, c.FanOut, c.Depth)
+		}
+	}
+	fmt.Println("Modules:")
+	fmt.Printf("  %-50s %5s %6s %7s %11s %9s\n", "MODULE", "DEPTH", "FAN-IN", "FAN-OUT", "BETWEENNESS", "DOMINATED")
+	for _, mm := range m.Metrics {
+		fmt.Printf("  %-50s %5d %6d %7d %11.3f %9d\n", mm.Path, mm.Depth, mm.FanIn, mm.FanOut, mm.Betweenness, mm.Dominated)
+	}
+}
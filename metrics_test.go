@@ -0,0 +1,176 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func newTestPathGraph(nodes []string, edges map[string][]string) *pathGraph {
+	g := &pathGraph{nodes: nodes, index: make(map[string]int), out: make([][]int, len(nodes))}
+	for i, n := range nodes {
+		g.index[n] = i
+	}
+	for from, tos := range edges {
+		for _, to := range tos {
+			g.out[g.index[from]] = append(g.out[g.index[from]], g.index[to])
+		}
+	}
+	return g
+}
+
+// bruteBetweenness computes betweenness from the definition: the fraction of
+// shortest s-t paths through v, summed over all pairs, with path counts
+// taken from a breadth-first search per source.
+func bruteBetweenness(g *pathGraph) []float64 {
+	n := len(g.nodes)
+	dist := make([][]int, n)
+	count := make([][]float64, n)
+	for s := 0; s < n; s++ {
+		dist[s] = g.distances(s, -1)
+		count[s] = make([]float64, n)
+		count[s][s] = 1
+		order := make([]int, 0, n)
+		for d := 0; d < n; d++ {
+			for v := 0; v < n; v++ {
+				if dist[s][v] == d {
+					order = append(order, v)
+				}
+			}
+		}
+		for _, v := range order {
+			for _, w := range g.out[v] {
+				if dist[s][w] == dist[s][v]+1 {
+					count[s][w] += count[s][v]
+				}
+			}
+		}
+	}
+	cb := make([]float64, n)
+	for s := 0; s < n; s++ {
+		for t := 0; t < n; t++ {
+			if s == t || dist[s][t] < 0 {
+				continue
+			}
+			for v := 0; v < n; v++ {
+				if v != s && v != t && dist[s][v] > 0 && dist[v][t] > 0 && dist[s][v]+dist[v][t] == dist[s][t] {
+					cb[v] += count[s][v] * count[v][t] / count[s][t]
+				}
+			}
+		}
+	}
+	if n > 2 {
+		for i := range cb {
+			cb[i] /= float64((n - 1) * (n - 2))
+		}
+	}
+	return cb
+}
+
+func TestBetweennessDiamond(t *testing.T) {
+	g := newTestPathGraph([]string{"m", "a", "b", "c", "d"}, map[string][]string{
+		"m": {"a", "b"},
+		"a": {"c"},
+		"b": {"c"},
+		"c": {"d"},
+	})
+	want := []float64{0, 1.0 / 12, 1.0 / 12, 3.0 / 12, 0}
+	got := g.betweenness()
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-12 {
+			t.Errorf("betweenness(%s) = %v, want %v", g.nodes[i], got[i], want[i])
+		}
+	}
+}
+
+func TestBetweennessRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		n := 2 + r.Intn(9)
+		nodes := make([]string, n)
+		for j := range nodes {
+			nodes[j] = string(rune('a' + j))
+		}
+		edges := make(map[string][]string)
+		for from := 0; from < n; from++ {
+			for to := 0; to < n; to++ {
+				if from != to && r.Intn(3) == 0 {
+					edges[nodes[from]] = append(edges[nodes[from]], nodes[to])
+				}
+			}
+		}
+		g := newTestPathGraph(nodes, edges)
+		got, want := g.betweenness(), bruteBetweenness(g)
+		for j := range want {
+			if math.Abs(got[j]-want[j]) > 1e-9 {
+				t.Fatalf("graph %v: betweenness(%s) = %v, want %v", edges, nodes[j], got[j], want[j])
+			}
+		}
+	}
+}
+
+func TestBuildPathGraph(t *testing.T) {
+	edges := []GraphEdge{
+		{"example.com/m", "example.com/a@v1.0.0"},
+		{"example.com/m", "example.com/c@v1.0.0"},
+		{"example.com/m", "example.com/e@v1.0.0"},
+		{"example.com/m", "go@1.21"},
+		{"example.com/a@v1.0.0", "example.com/c@v1.0.0"},
+		{"example.com/a@v1.0.0", "toolchain@go1.21.0"},
+		{"example.com/a@v0.9.0", "example.com/x@v1.0.0"},
+	}
+	modules := []ModuleInfo{
+		{Path: "example.com/m", Main: true},
+		{Path: "example.com/a", Version: "v1.0.0"},
+		{Path: "example.com/c", Version: "v1.0.0", Indirect: true},
+		{Path: "example.com/e", Version: "v1.0.0", Indirect: true},
+	}
+	g := buildPathGraph(edges, "example.com/m", modules)
+	got := make(map[string][]string)
+	for i, targets := range g.out {
+		for _, w := range targets {
+			got[g.nodes[i]] = append(got[g.nodes[i]], g.nodes[w])
+		}
+	}
+	want := map[string][]string{
+		"example.com/m": {"example.com/a", "example.com/e"},
+		"example.com/a": {"example.com/c"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("edges = %v, want %v", got, want)
+	}
+	if _, ok := g.index["example.com/x"]; ok {
+		t.Error("requirement of an unselected version kept")
+	}
+
+	metrics := computeGraphMetrics(edges, "example.com/m", modules, defaultCriticalModules)
+	if metrics.Modules != 3 || metrics.Edges != 3 || metrics.MaxDepth != 2 {
+		t.Errorf("metrics = %d modules, %d edges, depth %d", metrics.Modules, metrics.Edges, metrics.MaxDepth)
+	}
+	if len(metrics.Critical) != 1 || metrics.Critical[0].Path != "example.com/a" || metrics.Critical[0].Dominated != 1 {
+		t.Errorf("critical = %+v, want example.com/a dominating one module", metrics.Critical)
+	}
+}
+
+func TestDominatedModules(t *testing.T) {
+	// m requires a and b; both require c; c requires d. Removing c drops d,
+	// but removing a or b alone drops nothing.
+	edges := []GraphEdge{
+		{"m", "a@v1"}, {"m", "b@v1"},
+		{"a@v1", "c@v1"}, {"b@v1", "c@v1"},
+		{"c@v1", "d@v1"},
+	}
+	modules := []ModuleInfo{{Path: "m", Main: true}, {Path: "a", Version: "v1"}, {Path: "b", Version: "v1"}, {Path: "c", Version: "v1"}, {Path: "d", Version: "v1"}}
+	metrics := computeGraphMetrics(edges, "m", modules, 1)
+	dominated := make(map[string]int)
+	for _, m := range metrics.Metrics {
+		dominated[m.Path] = m.Dominated
+	}
+	if want := map[string]int{"a": 0, "b": 0, "c": 1, "d": 0}; !reflect.DeepEqual(dominated, want) {
+		t.Errorf("dominated = %v, want %v", dominated, want)
+	}
+	if len(metrics.Critical) != 1 || metrics.Critical[0].Path != "c" {
+		t.Errorf("critical = %+v, want only c", metrics.Critical)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type linkedModule struct {
+	Path    string
+	Version string
+	Dir     string
+}
+
+type LicenseText struct {
+	Text    string
+	Modules []string
+}
+
+type NoticeGroup struct {
+	License string
+	Texts   []*LicenseText
+}
+
+var licenseFilePrefixes = []string{"LICENSE", "LICENCE", "COPYING", "NOTICE"}
+
+func listLinkedModules(modDir string) ([]linkedModule, error) {
+	mains, err := listMainPackages(modDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(mains) == 0 {
+		return nil, fmt.Errorf("no main packages found")
+	}
+	args := append([]string{"list", "-mod=mod", "-deps", "-f", "{{with .Module}}{{if not .Main}}{{.Path}}\t{{.Version}}\t{{.Dir}}{{end}}{{end}}"}, mains...)
+	out, err := runGo(modDir, args...)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var modules []linkedModule
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 || seen[fields[0]] {
+			continue
+		}
+		seen[fields[0]] = true
+		modules = append(modules, linkedModule{Path: fields[0], Version: fields[1], Dir: fields[2]})
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Path < modules[j].Path })
+	return modules, nil
+}
+
+func findLicenseFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		upper := strings.ToUpper(e.Name())
+		for _, prefix := range licenseFilePrefixes {
+			if strings.HasPrefix(upper, prefix) {
+				files = append(files, filepath.Join(dir, e.Name()))
+				break
+			}
+		}
+	}
+	return files, nil
+}
+
+func classifyLicense(text string) string {
+	t := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	switch {
+	case strings.Contains(t, "apache license") && strings.Contains(t, "version 2.0"):
+		return "Apache-2.0"
+	case strings.Contains(t, "mozilla public license") && strings.Contains(t, "2.0"):
+		return "MPL-2.0"
+	case strings.Contains(t, "gnu lesser general public license"):
+		return "LGPL"
+	case strings.Contains(t, "gnu general public license"):
+		return "GPL"
+	case strings.Contains(t, "permission is hereby granted, free of charge"):
+		return "MIT"
+	case strings.Contains(t, "permission to use, copy, modify, and/or distribute this software for any purpose"):
+		return "ISC"
+	case strings.Contains(t, "redistribution and use in source and binary forms"):
+		if strings.Contains(t, "neither the name") || strings.Contains(t, "names of its contributors") {
+			return "BSD-3-Clause"
+		}
+		return "BSD-2-Clause"
+	case strings.Contains(t, "this is free and unencumbered software released into the public domain"):
+		return "Unlicense"
+	}
+	return "Unknown"
+}
+
+func collectNotices(modules []linkedModule) ([]NoticeGroup, []string) {
+	byText := make(map[string]*LicenseText)
+	groups := make(map[string][]*LicenseText)
+	var missing []string
+	for _, m := range modules {
+		name := m.Path + " " + m.Version
+		files, err := findLicenseFiles(m.Dir)
+		if err != nil || len(files) == 0 {
+			missing = append(missing, name)
+			continue
+		}
+		for _, f := range files {
+			data, err := os.ReadFile(f)
+			if err != nil {
+				continue
+			}
+			text := strings.TrimSpace(strings.ReplaceAll(string(data), "\r\n", "\n"))
+			lt, ok := byText[text]
+			if !ok {
+				lt = &LicenseText{Text: text}
+				byText[text] = lt
+				license := classifyLicense(text)
+				groups[license] = append(groups[license], lt)
+			}
+			if n := len(lt.Modules); n == 0 || lt.Modules[n-1] != name {
+				lt.Modules = append(lt.Modules, name)
+			}
+		}
+	}
+
+	var notices []NoticeGroup
+	for license, texts := range groups {
+		notices = append(notices, NoticeGroup{License: license, Texts: texts})
+	}
+	sort.Slice(notices, func(i, j int) bool { return notices[i].License < notices[j].License })
+	return notices, missing
+}
+
+func renderNoticeText(moduleName string, groups []NoticeGroup, missing []string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "THIRD-PARTY NOTICES for %s\n\n", moduleName)
+	fmt.Fprintf(&buf, "This software includes the following third-party modules.\n")
+	for _, g := range groups {
+		fmt.Fprintf(&buf, "\n%s\n%s\n", g.License, strings.Repeat("=", len(g.License)))
+		for _, t := range g.Texts {
+			buf.WriteString("\nUsed by:\n")
+			for _, m := range t.Modules {
+				fmt.Fprintf(&buf, "  - %s\n", m)
+			}
+			fmt.Fprintf(&buf, "\n%s\n\n%s\n", t.Text, strings.Repeat("-", 72))
+		}
+	}
+	if len(missing) > 0 {
+		buf.WriteString("\nModules without a license file:\n")
+		for _, m := range missing {
+			fmt.Fprintf(&buf, "  - %s\n", m)
+		}
+	}
+	return buf.Bytes()
+}
+
+var noticeHTML = template.Must(template.New("notice").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Third-party notices for {{.Module}}</title>
+</head>
+<body>
+<h1>Third-party notices for {{.Module}}</h1>
+{{range .Groups}}<h2>{{.License}}</h2>
+{{range .Texts}}<p>Used by:</p>
+<ul>
+{{range .Modules}}<li>{{.}}</li>
+{{end}}</ul>
+<pre>{{.Text}}</pre>
+{{end}}{{end}}{{if .Missing}}<h2>Modules without a license file</h2>
+<ul>
+{{range .Missing}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+`))
+
+func renderNoticeHTML(moduleName string, groups []NoticeGroup, missing []string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := noticeHTML.Execute(&buf, struct {
+		Module  string
+		Groups  []NoticeGroup
+		Missing []string
+	}{moduleName, groups, missing})
+	return buf.Bytes(), err
+}
+
+func writeNotices(modDir, moduleName, textPath, htmlPath string) error {
+	modules, err := listLinkedModules(modDir)
+	if err != nil {
+		return err
+	}
+	groups, missing := collectNotices(modules)
+	if textPath != "" {
+		if err := os.WriteFile(textPath, renderNoticeText(moduleName, groups, missing), 0o644); err != nil {
+			return err
+		}
+	}
+	if htmlPath != "" {
+		data, err := renderNoticeHTML(moduleName, groups, missing)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(htmlPath, data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClassifyLicense(t *testing.T) {
+	tests := []struct {
+		name, text, want string
+	}{
+		{"apache", "Apache License\n   Version 2.0, January 2004\n http://www.apache.org/licenses/", "Apache-2.0"},
+		{"mpl", "Mozilla Public License Version 2.0\n==================================", "MPL-2.0"},
+		{"lgpl", "GNU LESSER GENERAL PUBLIC LICENSE\n Version 3, 29 June 2007", "LGPL"},
+		{"gpl", "GNU GENERAL PUBLIC LICENSE\n Version 2, June 1991", "GPL"},
+		{"mit", "Copyright (c) 2020 X\n\nPermission is hereby granted, free of charge, to any person obtaining a copy", "MIT"},
+		{"isc", "Permission to use, copy, modify, and/or distribute this software for any\npurpose with or without fee is hereby granted", "ISC"},
+		{"bsd3", "Redistribution and use in source and binary forms, with or without\nmodification, are permitted...\n * Neither the name of Google Inc. nor the names", "BSD-3-Clause"},
+		{"bsd2", "Redistribution and use in source and binary forms, with or without\nmodification, are permitted provided that the following conditions are met:", "BSD-2-Clause"},
+		{"unlicense", "This is free and unencumbered software released into the public domain.", "Unlicense"},
+		{"unknown", "All rights reserved.", "Unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyLicense(tt.text); got != tt.want {
+				t.Errorf("classifyLicense = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectNotices(t *testing.T) {
+	root := t.TempDir()
+	mit := "Permission is hereby granted, free of charge, to any person"
+	write := func(dir, name, text string) string {
+		d := filepath.Join(root, dir)
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if name != "" {
+			if err := os.WriteFile(filepath.Join(d, name), []byte(text), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return d
+	}
+	modules := []linkedModule{
+		{Path: "example.com/a", Version: "v1.0.0", Dir: write("a", "LICENSE", mit+"\r\n")},
+		{Path: "example.com/b", Version: "v1.2.0", Dir: write("b", "license.md", mit)},
+		{Path: "example.com/c", Version: "v0.1.0", Dir: write("c", "README", "no license")},
+	}
+	groups, missing := collectNotices(modules)
+	if len(groups) != 1 || groups[0].License != "MIT" || len(groups[0].Texts) != 1 {
+		t.Fatalf("groups = %+v, want a single deduplicated MIT text", groups)
+	}
+	if got := strings.Join(groups[0].Texts[0].Modules, ","); got != "example.com/a v1.0.0,example.com/b v1.2.0" {
+		t.Errorf("MIT modules = %s", got)
+	}
+	if len(missing) != 1 || missing[0] != "example.com/c v0.1.0" {
+		t.Errorf("missing = %v", missing)
+	}
+}
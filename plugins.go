@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const pluginProtocolVersion = 1
+
+type pluginInput struct {
+	ProtocolVersion int     `json:"ProtocolVersion"`
+	Report          *Report `json:"Report"`
+}
+
+type pluginOutput struct {
+	Findings []Finding `json:"Findings"`
+}
+
+func discoverPlugins(dir string) ([]string, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var plugins []string
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0 {
+			plugins = append(plugins, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(plugins)
+	return plugins, nil
+}
+
+func runPlugin(path string, input []byte, timeout time.Duration) ([]Finding, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path)
+	cmd.WaitDelay = time.Second
+	cmd.Stdin = bytes.NewReader(input)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timed out after %s", timeout)
+		}
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var result pluginOutput
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		return nil, fmt.Errorf("invalid output: %v", err)
+	}
+	name := filepath.Base(path)
+	for i := range result.Findings {
+		f := &result.Findings[i]
+		if f.Source == "" {
+			f.Source = name
+		} else {
+			f.Source = name + "/" + f.Source
+		}
+		f.Severity = strings.ToLower(f.Severity)
+		if f.Severity == "" {
+			f.Severity = "warning"
+		}
+		if _, ok := severityRank[f.Severity]; !ok {
+			return nil, fmt.Errorf("finding %q has unknown severity %q", f.Message, f.Severity)
+		}
+	}
+	return result.Findings, nil
+}
+
+func runPlugins(plugins []string, report *Report, timeout time.Duration) ([]Finding, error) {
+	input, err := json.Marshal(pluginInput{ProtocolVersion: pluginProtocolVersion, Report: report})
+	if err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	for _, p := range plugins {
+		pf, err := runPlugin(p, input, timeout)
+		if err != nil {
+			findings = append(findings, Finding{
+				Source:   filepath.Base(p),
+				Severity: "error",
+				Message:  fmt.Sprintf("plugin failed: %v", err),
+			})
+			continue
+		}
+		findings = append(findings, pf...)
+	}
+	return findings, nil
+}
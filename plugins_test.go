@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writePlugin(t *testing.T, dir, name, script string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunPluginsDenyModules(t *testing.T) {
+	report := &Report{
+		Module: "example.com/m",
+		Modules: []ModuleInfo{
+			{Path: "example.com/m", Main: true},
+			{Path: "github.com/pkg/errors", Version: "v0.9.1"},
+			{Path: "golang.org/x/mod", Version: "v0.20.0"},
+		},
+	}
+	findings, err := runPlugins([]string{"testdata/plugins/deny-modules.sh"}, report, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Finding{{
+		Source:   "deny-modules.sh",
+		Module:   "github.com/pkg/errors",
+		Severity: "warning",
+		Message:  "github.com/pkg/errors is on the deny list",
+	}}
+	if !reflect.DeepEqual(findings, want) {
+		t.Errorf("findings = %+v, want %+v", findings, want)
+	}
+}
+
+func TestRunPluginsFailures(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, script, wantMessage string
+	}{
+		{"slow", "sleep 5\n", "plugin failed: timed out after 200ms"},
+		{"crash", "echo 'cannot read report' >&2\nexit 3\n", "plugin failed: exit status 3: cannot read report"},
+		{"garbage", "cat >/dev/null\necho 'not json'\n", "plugin failed: invalid output:"},
+		{"severity", `cat >/dev/null; echo '{"Findings":[{"Severity":"fatal","Message":"m"}]}'` + "\n", `plugin failed: finding "m" has unknown severity "fatal"`},
+	}
+	var plugins []string
+	for _, tt := range tests {
+		plugins = append(plugins, writePlugin(t, dir, tt.name, tt.script))
+	}
+	ok := writePlugin(t, dir, "ok", `cat >/dev/null; echo '{"Findings":[{"Source":"lint","Severity":"INFO","Message":"fine"}]}'`+"\n")
+
+	findings, err := runPlugins(append(plugins, ok), &Report{}, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != len(tests)+1 {
+		t.Fatalf("got %d findings, want %d: %+v", len(findings), len(tests)+1, findings)
+	}
+	for i, tt := range tests {
+		f := findings[i]
+		if f.Source != tt.name || f.Severity != "error" || !strings.HasPrefix(f.Message, tt.wantMessage) {
+			t.Errorf("%s: finding = %+v, want error %q", tt.name, f, tt.wantMessage)
+		}
+	}
+	if f := findings[len(tests)]; f != (Finding{Source: "ok/lint", Severity: "info", Message: "fine"}) {
+		t.Errorf("a failing plugin affected the next one: %+v", f)
+	}
+}
+
+func TestDiscoverPlugins(t *testing.T) {
+	dir := t.TempDir()
+	b := writePlugin(t, dir, "b", "")
+	a := writePlugin(t, dir, "a", "")
+	if err := os.WriteFile(filepath.Join(dir, "README"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	plugins, err := discoverPlugins(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{a, b}; !reflect.DeepEqual(plugins, want) {
+		t.Errorf("plugins = %v, want %v", plugins, want)
+	}
+}
+
+func TestPolicyViolations(t *testing.T) {
+	findings := []Finding{{Severity: "info"}, {Severity: "warning"}, {Severity: "error"}}
+	tests := map[string]int{"": 0, "info": 3, "warning": 2, "error": 1}
+	for failOn, want := range tests {
+		if got := len(policyViolations(findings, failOn)); got != want {
+			t.Errorf("policyViolations(%q) = %d findings, want %d", failOn, got, want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/mod/semver"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const defaultPriorityWeights = "vulns=5,libyear=2,semver=1,direct=1.5,usage=1,coverage=1"
+
+var prioritySignals = []string{"vulns", "libyear", "semver", "direct", "usage", "coverage"}
+
+type PriorityFactor struct {
+	Signal string  `json:"Signal"`
+	Value  string  `json:"Value"`
+	Score  float64 `json:"Score"`
+}
+
+type UpdatePriority struct {
+	Path    string           `json:"Path"`
+	From    string           `json:"From"`
+	To      string           `json:"To"`
+	Score   float64          `json:"Score"`
+	Factors []PriorityFactor `json:"Factors"`
+}
+
+// parsePriorityWeights overrides the default weights with the signal=weight
+// pairs in s.
+func parsePriorityWeights(s string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, signal := range prioritySignals {
+		weights[signal] = 0
+	}
+	if err := setPriorityWeights(weights, defaultPriorityWeights); err != nil {
+		return nil, err
+	}
+	if err := setPriorityWeights(weights, s); err != nil {
+		return nil, err
+	}
+	return weights, nil
+}
+
+func setPriorityWeights(weights map[string]float64, s string) error {
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(field, "=")
+		if _, known := weights[name]; !ok || !known {
+			return fmt.Errorf("expected signal=weight with signal one of %s, got %q", strings.Join(prioritySignals, ", "), field)
+		}
+		w, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("bad weight for %s: %v", name, err)
+		}
+		weights[name] = w
+	}
+	return nil
+}
+
+// semverEase favors updates that are cheap to take.
+var semverEase = map[string]float64{"patch": 1, "minor": 0.6, "major": 0.2, "prerelease": 0.1}
+
+// prioritizeUpdates scores every upgradable dependency. Each signal is
+// normalized to [0, 1] before it is weighted, so the weights express the
+// relative importance of the signals.
+func prioritizeUpdates(deps []ModuleInfo, usage map[string]int, vulns map[string][]Vulnerability, weights map[string]float64) []UpdatePriority {
+	maxUsage := 0
+	for _, n := range usage {
+		maxUsage = max(maxUsage, n)
+	}
+
+	var priorities []UpdatePriority
+	for _, dep := range deps {
+		if !upgradable(dep) {
+			continue
+		}
+		p := UpdatePriority{Path: dep.Path, From: dep.Version, To: dep.Update.Version}
+		add := func(signal, value string, normalized float64) {
+			f := PriorityFactor{Signal: signal, Value: value, Score: weights[signal] * normalized}
+			p.Factors = append(p.Factors, f)
+			p.Score += f.Score
+		}
+
+		fixed := 0
+		for _, v := range vulns[dep.Path] {
+			if v.Fixed != "" && semver.Compare(v.Fixed, dep.Update.Version) <= 0 {
+				fixed++
+			}
+		}
+		add("vulns", fmt.Sprintf("%d fixed", fixed), min(float64(fixed), 3)/3)
+
+		years := libyear(dep)
+		add("libyear", fmt.Sprintf("%.2f years", years), years/(1+years))
+
+		class := updateClass(dep)
+		add("semver", class, semverEase[class])
+
+		if dep.Indirect {
+			add("direct", "indirect", 0)
+		} else {
+			add("direct", "direct", 1)
+		}
+
+		sites := usage[dep.Path]
+		var usageScore float64
+		if maxUsage > 0 {
+			usageScore = float64(sites) / float64(maxUsage)
+		}
+		add("usage", fmt.Sprintf("%d call sites", sites), usageScore)
+
+		if dep.Coverage != nil && dep.Coverage.Sites > 0 {
+			add("coverage", fmt.Sprintf("%.1f%%", dep.Coverage.Percent()), dep.Coverage.Percent()/100)
+		} else {
+			add("coverage", "n/a", 0)
+		}
+		priorities = append(priorities, p)
+	}
+	sort.SliceStable(priorities, func(i, j int) bool { return priorities[i].Score > priorities[j].Score })
+	return priorities
+}
+
+// rankUpdates attaches each update's priority to its module and orders deps
+// by descending score. Modules without a priority keep their order at the end.
+func rankUpdates(deps []ModuleInfo, priorities []UpdatePriority) {
+	byPath := make(map[string]*UpdatePriority)
+	for i := range priorities {
+		byPath[priorities[i].Path] = &priorities[i]
+	}
+	for i := range deps {
+		deps[i].Priority = byPath[deps[i].Path]
+	}
+	sort.SliceStable(deps, func(i, j int) bool {
+		a, b := deps[i].Priority, deps[j].Priority
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return a.Score > b.Score
+	})
+}
+
+func dependencyUsage(modDir string) (map[string]int, error) {
+	modules, err := listModules(modDir, false)
+	if err != nil {
+		return nil, err
+	}
+	sites, err := findDependencyCallSites(modDir, modules)
+	if err != nil {
+		return nil, err
+	}
+	usage := make(map[string]int)
+	for _, site := range sites {
+		usage[site.module]++
+	}
+	return usage, nil
+}
+
+func lookupVulnerabilities(deps []ModuleInfo) map[string][]Vulnerability {
+	db := newVulnDB()
+	vulns := make(map[string][]Vulnerability)
+	for _, dep := range deps {
+		if !upgradable(dep) {
+			continue
+		}
+		found, err := db.Vulnerabilities(dep.Path, dep.Version)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: vulnerability lookup failed, ignoring the vulns signal: %v\n", err)
+			return nil
+		}
+		vulns[dep.Path] = found
+	}
+	return vulns
+}
+
+func printPriorities(priorities []UpdatePriority) {
+	if len(priorities) == 0 {
+		return
+	}
+	fmt.Println("Update priority:")
+	for i, p := range priorities {
+		fmt.Printf("%d. %s: %s -> %s (score %.2f)\n", i+1, p.Path, p.From, p.To, p.Score)
+		var parts []string
+		for _, f := range p.Factors {
+			parts = append(parts, fmt.Sprintf("%s %s [%.2f]", f.Signal, f.Value, f.Score))
+		}
+		fmt.Printf("    %s\n", strings.Join(parts, ", "))
+	}
+}
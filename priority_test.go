@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/mod/modfile"
+	"strings"
+	"testing"
+)
+
+func TestRankUpdates(t *testing.T) {
+	deps := []ModuleInfo{
+		withUpdate(ModuleInfo{Path: "example.com/a", Version: "v1.0.0", Indirect: true}, "v2.0.0", nil),
+		{Path: "example.com/retracted", Version: "v1.0.0", Retracted: []string{"bad"}},
+		withUpdate(ModuleInfo{Path: "example.com/b", Version: "v1.0.0"}, "v1.0.1", nil),
+		withUpdate(ModuleInfo{Path: "example.com/c", Version: "v1.0.0"}, "v1.1.0", nil),
+	}
+	usage := map[string]int{"example.com/c": 4, "example.com/b": 1}
+	weights, err := parsePriorityWeights("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	priorities := prioritizeUpdates(deps, usage, nil, weights)
+	rankUpdates(deps, priorities)
+
+	var order []string
+	for _, d := range deps {
+		order = append(order, d.Path)
+	}
+	want := []string{"example.com/c", "example.com/b", "example.com/a", "example.com/retracted"}
+	if !equalStrings(order, want) {
+		t.Fatalf("update order = %v, want %v", order, want)
+	}
+	for i, d := range deps[:3] {
+		if d.Priority == nil || d.Priority.Path != d.Path || d.Priority.Score != priorities[i].Score {
+			t.Errorf("%s: priority %+v, want %+v", d.Path, d.Priority, priorities[i])
+		}
+	}
+	if deps[3].Priority != nil {
+		t.Errorf("module without update got priority %+v", deps[3].Priority)
+	}
+
+	if got := updateAnnotations(deps[0]); !strings.Contains(got, "priority score ") {
+		t.Errorf("annotations = %q, want the priority score", got)
+	}
+	modFile, err := modfile.Parse("go.mod", []byte("module example.com/m\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues := ciIssues(&Report{Updates: deps}, modFile, "go.mod")
+	if len(issues) == 0 || !strings.HasSuffix(issues[0].Message, fmt.Sprintf(" (priority score %.2f)", deps[0].Priority.Score)) {
+		t.Errorf("CI issues = %+v, want the priority score in the first message", issues)
+	}
+}
+
+func TestPriorityCoverageSignal(t *testing.T) {
+	dep := withUpdate(ModuleInfo{Path: "example.com/a", Version: "v1.0.0"}, "v1.0.1", nil)
+	weights, err := parsePriorityWeights("vulns=0,libyear=0,semver=0,direct=0,usage=0,coverage=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := prioritizeUpdates([]ModuleInfo{dep}, nil, nil, weights); p[0].Score != 0 {
+		t.Errorf("score without coverage = %v, want 0", p[0].Score)
+	}
+	dep.Coverage = &CallSiteCoverage{Sites: 4, Covered: 3}
+	if p := prioritizeUpdates([]ModuleInfo{dep}, nil, nil, weights); p[0].Score != 1.5 {
+		t.Errorf("score with 75%% coverage = %v, want 1.5", p[0].Score)
+	}
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/version"
+	"golang.org/x/mod/modfile"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type ModuleQuality struct {
+	Grade          string  `json:"Grade"`
+	Score          float64 `json:"Score"`
+	GoFiles        int     `json:"GoFiles"`
+	TestFiles      int     `json:"TestFiles"`
+	GeneratedFiles int     `json:"GeneratedFiles"`
+	License        bool    `json:"License"`
+	VetFindings    int     `json:"VetFindings"`
+	VetChecked     bool    `json:"VetChecked"`
+	Exported       int     `json:"Exported"`
+	Documented     int     `json:"Documented"`
+	GoVersion      string  `json:"GoVersion,omitempty"`
+}
+
+var qualityGrades = []struct {
+	min   float64
+	grade string
+}{{0.85, "A"}, {0.7, "B"}, {0.55, "C"}, {0.4, "D"}, {0, "F"}}
+
+var vetDiagnostic = regexp.MustCompile(`^(.+\.go):\d+:\d+: (.*)$`)
+
+// analyzeModuleQuality inspects the module cache copies of the build list
+// without touching the network and sets the Quality of each module found.
+func analyzeModuleQuality(modDir string, modules []ModuleInfo) error {
+	goVersion, err := runGo(modDir, "env", "GOVERSION")
+	if err != nil {
+		return err
+	}
+	current := strings.TrimPrefix(strings.TrimSpace(string(goVersion)), "go")
+	vet, vetChecked := vetFindings(modDir, modules)
+	for i := range modules {
+		m := &modules[i]
+		if m.Main || m.Dir == "" {
+			continue
+		}
+		q, err := inspectModuleDir(m.Dir)
+		if err != nil {
+			return fmt.Errorf("%s: %v", m.Path, err)
+		}
+		q.VetChecked = vetChecked[m.Path]
+		q.VetFindings = vet[m.Path]
+		q.Score = qualityScore(q, current)
+		q.Grade = qualityGrade(q.Score)
+		m.Quality = q
+	}
+	return nil
+}
+
+func inspectModuleDir(root string) (*ModuleQuality, error) {
+	q := &ModuleQuality{}
+	licenses, err := findLicenseFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	q.License = len(licenses) > 0
+	if data, err := os.ReadFile(filepath.Join(root, "go.mod")); err == nil {
+		if f, err := modfile.ParseLax("go.mod", data, nil); err == nil && f.Go != nil {
+			q.GoVersion = f.Go.Version
+		}
+	}
+
+	fset := token.NewFileSet()
+	err = walkModuleGoFiles(root, func(path string) error {
+		if strings.HasSuffix(path, "_test.go") {
+			q.TestFiles++
+			return nil
+		}
+		q.GoFiles++
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil
+		}
+		if ast.IsGenerated(file) {
+			q.GeneratedFiles++
+			return nil
+		}
+		exported, documented := docCoverage(file)
+		q.Exported += exported
+		q.Documented += documented
+		return nil
+	})
+	return q, err
+}
+
+// walkModuleGoFiles calls fn for every Go file of the module at root,
+// skipping testdata, vendor, hidden and underscore directories as well as
+// nested modules.
+func walkModuleGoFiles(root string, fn func(path string) error) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			if path != root {
+				if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		return fn(path)
+	})
+}
+
+// docCoverage counts the exported top-level identifiers of a file and how
+// many of them have a doc comment. A comment on a grouped declaration
+// documents every identifier in the group.
+func docCoverage(file *ast.File) (exported, documented int) {
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() || (d.Recv != nil && !exportedReceiver(d.Recv)) {
+				continue
+			}
+			exported++
+			if d.Doc != nil {
+				documented++
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				var names []*ast.Ident
+				var doc *ast.CommentGroup
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					names, doc = []*ast.Ident{s.Name}, s.Doc
+				case *ast.ValueSpec:
+					names, doc = s.Names, s.Doc
+				}
+				for _, name := range names {
+					if !name.IsExported() {
+						continue
+					}
+					exported++
+					if doc != nil || d.Doc != nil {
+						documented++
+					}
+				}
+			}
+		}
+	}
+	return exported, documented
+}
+
+func exportedReceiver(recv *ast.FieldList) bool {
+	if len(recv.List) == 0 {
+		return false
+	}
+	t := recv.List[0].Type
+	for {
+		switch x := t.(type) {
+		case *ast.StarExpr:
+			t = x.X
+		case *ast.IndexExpr:
+			t = x.X
+		case *ast.IndexListExpr:
+			t = x.X
+		case *ast.Ident:
+			return x.IsExported()
+		default:
+			return false
+		}
+	}
+}
+
+// vetFindings runs go vet over the dependency packages that load with the
+// proxy disabled and counts the diagnostics per module. A module counts as
+// checked only if all of its packages loaded; modules that failed to load,
+// or have no packages, are left out of checked rather than reported clean.
+func vetFindings(modDir string, modules []ModuleInfo) (findings map[string]int, checked map[string]bool) {
+	var patterns []string
+	for _, m := range modules {
+		if !m.Main && m.Dir != "" {
+			patterns = append(patterns, m.Path+"/...")
+		}
+	}
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	pkgs, checked, err := loadVetPackages(modDir, patterns)
+	if err != nil || len(pkgs) == 0 {
+		return nil, nil
+	}
+
+	sp := startSpan("go vet", "module.count", strconv.Itoa(len(checked)))
+	cmd := exec.Command("go", append([]string{"vet"}, pkgs...)...)
+	cmd.Dir = modDir
+	cmd.Env = append(os.Environ(), "GOPROXY=off", "GOFLAGS=-mod=mod")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err = cmd.Run()
+	sp.End(err)
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		return nil, nil
+	}
+
+	findings = make(map[string]int)
+	for _, line := range strings.Split(out.String(), "\n") {
+		match := vetDiagnostic.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		file := match[1]
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(modDir, file)
+		}
+		var best ModuleInfo
+		for _, m := range modules {
+			if m.Dir != "" && strings.HasPrefix(file, m.Dir+string(filepath.Separator)) && len(m.Dir) > len(best.Dir) {
+				best = m
+			}
+		}
+		if best.Path != "" && !best.Main {
+			findings[best.Path]++
+		}
+	}
+	if err != nil && len(findings) == 0 {
+		// vet failed without reporting a diagnostic, so it did not get as far
+		// as analyzing the packages.
+		fmt.Fprintf(os.Stderr, "warning: go vet failed, skipping the vet signal: %s\n", strings.TrimSpace(out.String()))
+		return nil, nil
+	}
+	return findings, checked
+}
+
+// loadVetPackages lists the packages matching patterns and returns those
+// that loaded without errors, along with the modules whose packages all
+// loaded.
+func loadVetPackages(modDir string, patterns []string) (pkgs []string, loaded map[string]bool, err error) {
+	cmd := exec.Command("go", append([]string{"list", "-e", "-json=ImportPath,Module,Error,DepsErrors"}, patterns...)...)
+	cmd.Dir = modDir
+	cmd.Env = append(os.Environ(), "GOPROXY=off", "GOFLAGS=-mod=mod")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil, nil, err
+	}
+
+	var ok []struct{ pkg, module string }
+	broken := make(map[string]bool)
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var p struct {
+			ImportPath string
+			Module     *struct{ Path string }
+			Error      *struct{ Err string }
+			DepsErrors []struct{ Err string }
+		}
+		if err := dec.Decode(&p); err != nil {
+			return nil, nil, err
+		}
+		if p.Module == nil {
+			continue
+		}
+		if p.Error != nil || len(p.DepsErrors) > 0 {
+			broken[p.Module.Path] = true
+			continue
+		}
+		ok = append(ok, struct{ pkg, module string }{p.ImportPath, p.Module.Path})
+	}
+	loaded = make(map[string]bool)
+	for _, p := range ok {
+		if !broken[p.module] {
+			pkgs = append(pkgs, p.pkg)
+			loaded[p.module] = true
+		}
+	}
+	return pkgs, loaded, nil
+}
+
+// qualityScore averages the signals, each normalized to [0, 1]. Modules
+// without non-generated Go code are not penalized for documentation.
+func qualityScore(q *ModuleQuality, currentGo string) float64 {
+	var signals []float64
+	if q.GoFiles > 0 {
+		signals = append(signals, min(float64(q.TestFiles)/float64(q.GoFiles)/0.5, 1))
+		signals = append(signals, 1-float64(q.GeneratedFiles)/float64(q.GoFiles)/2)
+	} else {
+		signals = append(signals, 0)
+	}
+	if q.License {
+		signals = append(signals, 1)
+	} else {
+		signals = append(signals, 0)
+	}
+	if q.VetChecked {
+		signals = append(signals, 1/(1+float64(q.VetFindings)))
+	}
+	if q.Exported > 0 {
+		signals = append(signals, float64(q.Documented)/float64(q.Exported))
+	}
+	signals = append(signals, goVersionRecency(q.GoVersion, currentGo))
+
+	var sum float64
+	for _, s := range signals {
+		sum += s
+	}
+	return sum / float64(len(signals))
+}
+
+func qualityGrade(score float64) string {
+	for _, g := range qualityGrades {
+		if score >= g.min {
+			return g.grade
+		}
+	}
+	return qualityGrades[len(qualityGrades)-1].grade
+}
+
+// goVersionRecency scores the go directive: full marks within two minor
+// releases of the current toolchain, half within four.
+func goVersionRecency(declared, current string) float64 {
+	if declared == "" || !version.IsValid("go"+declared) {
+		return 0
+	}
+	behind := goMinor(current) - goMinor(declared)
+	switch {
+	case behind <= 2:
+		return 1
+	case behind <= 4:
+		return 0.5
+	}
+	return 0
+}
+
+func goMinor(v string) int {
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) < 2 {
+		return 0
+	}
+	minor := parts[1]
+	if i := strings.IndexFunc(minor, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		minor = minor[:i]
+	}
+	n, _ := strconv.Atoi(minor)
+	return n
+}
+
+func printModuleQuality(modules []ModuleInfo) {
+	fmt.Println("Module quality:")
+	for _, m := range modules {
+		if m.Main {
+			continue
+		}
+		q := m.Quality
+		if q == nil {
+			fmt.Printf("- %s %s: not in module cache\n", m.Path, m.Version)
+			continue
+		}
+		vet := "vet n/a"
+		if q.VetChecked {
+			vet = fmt.Sprintf("%d vet findings", q.VetFindings)
+		}
+		docs := "no exported API"
+		if q.Exported > 0 {
+			docs = fmt.Sprintf("%.0f%% documented", float64(q.Documented)/float64(q.Exported)*100)
+		}
+		license := "license"
+		if !q.License {
+			license = "no license"
+		}
+		goVersion := q.GoVersion
+		if goVersion == "" {
+			goVersion = "none"
+		}
+		fmt.Printf("- %s %s: %s (%.2f) - %d/%d test/source files, %d generated, %s, %s, %s, go %s\n",
+			m.Path, m.Version, q.Grade, q.Score, q.TestFiles, q.GoFiles, q.GeneratedFiles, license, vet, docs, goVersion)
+	}
+}
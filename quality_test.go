@@ -0,0 +1,258 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestVetFindings(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": `module example.com/m
+
+go 1.21
+
+require (
+	example.com/bad v0.0.0
+	example.com/empty v0.0.0
+	example.com/good v0.0.0
+	example.com/vetted v0.0.0
+)
+
+replace (
+	example.com/bad => ./bad
+	example.com/empty => ./empty
+	example.com/good => ./good
+	example.com/vetted => ./vetted
+)
+`,
+		"m.go":          "package m\n",
+		"good/go.mod":   "module example.com/good\n\ngo 1.21\n",
+		"good/good.go":  "package good\n\nfunc Good() int { return 1 }\n",
+		"vetted/go.mod": "module example.com/vetted\n\ngo 1.21\n",
+		"vetted/v.go": `package vetted
+
+import "fmt"
+
+func Vetted() string { return fmt.Sprintf("%d", "not a number") }
+`,
+		"bad/go.mod":   "module example.com/bad\n\ngo 1.21\n",
+		"bad/ok/ok.go": "package ok\n",
+		"bad/bad.go":   "package bad\n\nimport _ \"example.com/missing\"\n",
+		"empty/go.mod": "module example.com/empty\n\ngo 1.21\n",
+		"empty/README": "no code\n",
+	})
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Setenv("GOPROXY", "off")
+	modules, err := listModules(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings, checked := vetFindings(dir, modules)
+	if want := map[string]int{"example.com/vetted": 1}; !reflect.DeepEqual(findings, want) {
+		t.Errorf("findings = %v, want %v", findings, want)
+	}
+	if want := map[string]bool{"example.com/good": true, "example.com/vetted": true}; !reflect.DeepEqual(checked, want) {
+		t.Errorf("checked = %v, want %v; modules that fail to load or have no packages must not count as vetted", checked, want)
+	}
+}
+
+func TestDocCoverage(t *testing.T) {
+	src := `package p
+
+// Documented is documented.
+func Documented() {}
+
+func Undocumented() {}
+
+func unexported() {}
+
+// Grouped declarations are documented by the comment on the group.
+const (
+	A = iota
+	B
+	c
+)
+
+var (
+	// X has its own comment.
+	X int
+	Y int
+)
+
+var Z, W int
+
+type (
+	// T is documented.
+	T struct{}
+	U struct{}
+	g[P any] struct{}
+)
+
+// M is a method of an exported type.
+func (T) M() {}
+
+func (*U) M() {}
+
+// M is a method of an unexported type and does not count.
+func (g[P]) M() {}
+
+// Get is a method of an exported generic type.
+func (*G[K, V]) Get() {}
+
+func (G[K, V]) Set() {}
+
+type G[K comparable, V any] struct{}
+`
+	file, err := parser.ParseFile(token.NewFileSet(), "p.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Exported: Documented, Undocumented, A, B, X, Y, Z, W, T, U, T.M,
+	// U.M, G.Get, G.Set and G. Documented: Documented, A, B, X, T, T.M and
+	// G.Get.
+	exported, documented := docCoverage(file)
+	if exported != 15 || documented != 7 {
+		t.Errorf("docCoverage = %d exported, %d documented; want 15, 7", exported, documented)
+	}
+}
+
+func TestExportedReceiver(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"func (T) M() {}", true},
+		{"func (*T) M() {}", true},
+		{"func (t *t) M() {}", false},
+		{"func (G[P]) M() {}", true},
+		{"func (*G[K, V]) M() {}", true},
+		{"func (*g[K, V]) M() {}", false},
+	}
+	for _, tt := range tests {
+		file, err := parser.ParseFile(token.NewFileSet(), "p.go", "package p\n\n"+tt.src+"\n", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recv := file.Decls[0].(*ast.FuncDecl).Recv
+		if got := exportedReceiver(recv); got != tt.want {
+			t.Errorf("exportedReceiver(%s) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestInspectModuleDir(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.mod":        "module example.com/a\n\ngo 1.21\n",
+		"LICENSE":       "MIT License\n",
+		"a.go":          "package a\n\n// F is documented.\nfunc F() {}\n\nfunc G() {}\n",
+		"a_test.go":     "package a\n",
+		"a.pb.go":       "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage a\n\nfunc Generated() {}\n",
+		"broken.go":     "package a\n\nfunc {\n",
+		"sub/s.go":      "package sub\n\n// S is documented.\ntype S int\n",
+		"testdata/x.go": "package x\n\nfunc X() {}\n",
+		"vendor/v/v.go": "package v\n\nfunc V() {}\n",
+		"_tools/t.go":   "package t\n\nfunc T() {}\n",
+		"nested/go.mod": "module example.com/a/nested\n",
+		"nested/n.go":   "package n\n\nfunc N() {}\n",
+	})
+	q, err := inspectModuleDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ModuleQuality{
+		GoFiles:        4,
+		TestFiles:      1,
+		GeneratedFiles: 1,
+		License:        true,
+		Exported:       3,
+		Documented:     2,
+		GoVersion:      "1.21",
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("inspectModuleDir =\n%+v\nwant\n%+v", q, want)
+	}
+}
+
+func TestGoVersionRecency(t *testing.T) {
+	tests := []struct {
+		declared, current string
+		want              float64
+	}{
+		{"1.23", "1.23.2", 1},
+		{"1.21", "1.23.2", 1},
+		{"1.20", "1.23.2", 0.5},
+		{"1.19.5", "1.23.2", 0.5},
+		{"1.18", "1.23.2", 0},
+		{"1.24rc1", "1.23.2", 1},
+		{"1.21rc2", "1.25.0", 0.5},
+		{"", "1.23.2", 0},
+		{"bogus", "1.23.2", 0},
+	}
+	for _, tt := range tests {
+		if got := goVersionRecency(tt.declared, tt.current); got != tt.want {
+			t.Errorf("goVersionRecency(%q, %q) = %v, want %v", tt.declared, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestQualityScore(t *testing.T) {
+	tests := []struct {
+		name  string
+		q     ModuleQuality
+		score float64
+		grade string
+	}{
+		{
+			name:  "tested, licensed, vetted and documented",
+			q:     ModuleQuality{GoFiles: 4, TestFiles: 2, License: true, VetChecked: true, Exported: 10, Documented: 10, GoVersion: "1.23"},
+			score: 1,
+			grade: "A",
+		},
+		{
+			name: "vet findings and missing docs",
+			// tests 1, generated 1, license 1, vet 1/3, docs 0.5, go 1
+			q:     ModuleQuality{GoFiles: 2, TestFiles: 1, License: true, VetChecked: true, VetFindings: 2, Exported: 4, Documented: 2, GoVersion: "1.22"},
+			score: (1 + 1 + 1 + 1.0/3 + 0.5 + 1) / 6,
+			grade: "B",
+		},
+		{
+			name: "unchecked vet and no exported API are left out",
+			// tests 0.5, generated 0.75, license 0, go 0.5
+			q:     ModuleQuality{GoFiles: 4, TestFiles: 1, GeneratedFiles: 2, GoVersion: "1.20"},
+			score: (0.5 + 0.75 + 0 + 0.5) / 4,
+			grade: "D",
+		},
+		{
+			name:  "no Go files",
+			q:     ModuleQuality{License: true, GoVersion: "1.23"},
+			score: (0 + 1 + 1) / 3.0,
+			grade: "C",
+		},
+		{
+			name:  "nothing",
+			q:     ModuleQuality{},
+			score: 0,
+			grade: "F",
+		},
+	}
+	for _, tt := range tests {
+		score := qualityScore(&tt.q, "1.23.2")
+		if math.Abs(score-tt.score) > 1e-9 {
+			t.Errorf("%s: score = %v, want %v", tt.name, score, tt.score)
+		}
+		if grade := qualityGrade(score); grade != tt.grade {
+			t.Errorf("%s: grade = %s, want %s", tt.name, grade, tt.grade)
+		}
+	}
+}
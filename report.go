@@ -0,0 +1,66 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+type Report struct {
+	Module       string             `json:"Module"`
+	GoVersion    string             `json:"GoVersion"`
+	Toolchain    string             `json:"Toolchain,omitempty"`
+	Modules      []ModuleInfo       `json:"Modules"`
+	Updates      []ModuleInfo       `json:"Updates"`
+	Graph        []GraphEdge        `json:"Graph,omitempty"`
+	Findings     []Finding          `json:"Findings,omitempty"`
+	Priorities   []UpdatePriority   `json:"Priorities,omitempty"`
+	Replacements []StdlibSuggestion `json:"Replacements,omitempty"`
+}
+
+type GraphEdge struct {
+	From string `json:"From"`
+	To   string `json:"To"`
+}
+
+type Finding struct {
+	Source   string `json:"Source"`
+	Module   string `json:"Module,omitempty"`
+	Severity string `json:"Severity"`
+	Message  string `json:"Message"`
+}
+
+var severityRank = map[string]int{"info": 1, "warning": 2, "error": 3}
+
+func getModuleGraph(dir string) ([]GraphEdge, error) {
+	out, err := runGo(dir, "mod", "graph")
+	if err != nil {
+		return nil, err
+	}
+	var edges []GraphEdge
+	for _, line := range strings.Split(string(out), "\n") {
+		if from, to, ok := strings.Cut(strings.TrimSpace(line), " "); ok {
+			edges = append(edges, GraphEdge{From: from, To: to})
+		}
+	}
+	return edges, nil
+}
+
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank[findings[i].Severity] > severityRank[findings[j].Severity]
+	})
+}
+
+func policyViolations(findings []Finding, failOn string) []Finding {
+	threshold, ok := severityRank[failOn]
+	if !ok {
+		return nil
+	}
+	var violations []Finding
+	for _, f := range findings {
+		if severityRank[f.Severity] >= threshold {
+			violations = append(violations, f)
+		}
+	}
+	return violations
+}
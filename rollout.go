@@ -0,0 +1,333 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"golang.org/x/mod/semver"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type RolloutBump struct {
+	Path string
+	From string
+	To   string
+}
+
+type RolloutStep struct {
+	Repo   string
+	Module string
+	Bumps  []RolloutBump
+	Tag    string
+}
+
+type moduleGraph struct {
+	repos map[string]FleetRepo
+	deps  map[string]map[string]string
+}
+
+func newModuleGraph(index *FleetIndex) *moduleGraph {
+	g := &moduleGraph{repos: make(map[string]FleetRepo), deps: make(map[string]map[string]string)}
+	for _, repo := range index.Repos {
+		g.repos[repo.Module] = repo
+	}
+	for _, repo := range index.Repos {
+		reqs := make(map[string]string)
+		for _, r := range repo.Requires {
+			if _, ok := g.repos[r.Path]; ok && r.Path != repo.Module {
+				reqs[r.Path] = r.Version
+			}
+		}
+		g.deps[repo.Module] = reqs
+	}
+	return g
+}
+
+func (g *moduleGraph) modules() []string {
+	mods := make([]string, 0, len(g.repos))
+	for m := range g.repos {
+		mods = append(mods, m)
+	}
+	sort.Strings(mods)
+	return mods
+}
+
+// cycles returns the strongly connected components with more than one
+// module, found with Tarjan's algorithm.
+func (g *moduleGraph) cycles() [][]string {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var result [][]string
+	next := 0
+
+	var visit func(m string)
+	visit = func(m string) {
+		index[m], low[m] = next, next
+		next++
+		stack = append(stack, m)
+		onStack[m] = true
+		for _, dep := range sortedKeys(g.deps[m]) {
+			if _, seen := index[dep]; !seen {
+				visit(dep)
+				low[m] = min(low[m], low[dep])
+			} else if onStack[dep] {
+				low[m] = min(low[m], index[dep])
+			}
+		}
+		if low[m] != index[m] {
+			return
+		}
+		var scc []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			scc = append(scc, top)
+			if top == m {
+				break
+			}
+		}
+		if len(scc) > 1 {
+			sort.Strings(scc)
+			result = append(result, scc)
+		}
+	}
+	for _, m := range g.modules() {
+		if _, seen := index[m]; !seen {
+			visit(m)
+		}
+	}
+	return result
+}
+
+// stages orders the given modules so that every module comes after the
+// internal modules it requires. Modules in the same stage can be updated
+// in parallel.
+func (g *moduleGraph) stages(set map[string]bool) ([][]string, error) {
+	remaining := make(map[string]bool, len(set))
+	for m := range set {
+		remaining[m] = true
+	}
+	var stages [][]string
+	for len(remaining) > 0 {
+		var stage []string
+		for m := range remaining {
+			ready := true
+			for dep := range g.deps[m] {
+				if remaining[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				stage = append(stage, m)
+			}
+		}
+		if len(stage) == 0 {
+			return nil, fmt.Errorf("dependency cycle among internal modules")
+		}
+		sort.Strings(stage)
+		for _, m := range stage {
+			delete(remaining, m)
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+func (g *moduleGraph) dependents(module string) map[string]bool {
+	affected := map[string]bool{module: true}
+	queue := []string{module}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, m := range g.modules() {
+			if _, ok := g.deps[m][cur]; ok && !affected[m] {
+				affected[m] = true
+				queue = append(queue, m)
+			}
+		}
+	}
+	return affected
+}
+
+func (g *moduleGraph) currentRelease(module string) string {
+	if v, err := latestVersion(module); err == nil && semver.IsValid(v) {
+		return v
+	}
+	var best string
+	for _, reqs := range g.deps {
+		if v, ok := reqs[module]; ok && (best == "" || semver.Compare(v, best) > 0) {
+			best = v
+		}
+	}
+	return best
+}
+
+// nextVersion returns the tag that follows current for the given bump. A
+// prerelease or pseudo-version already leads to a release, so that release is
+// the next tag unless a minor bump asks for more than the patch it would be.
+func nextVersion(current, bump string) string {
+	if current == "" || !semver.IsValid(current) {
+		return "v0.1.0"
+	}
+	v := semver.Canonical(current)
+	pre := semver.Prerelease(v)
+	parts := strings.SplitN(strings.TrimPrefix(strings.TrimSuffix(v, pre), "v"), ".", 3)
+	major, _ := strconv.Atoi(parts[0])
+	minor, _ := strconv.Atoi(parts[1])
+	patch, _ := strconv.Atoi(parts[2])
+	if pre != "" && major+minor+patch > 0 {
+		if bump == "minor" && patch > 0 {
+			return fmt.Sprintf("v%d.%d.0", major, minor+1)
+		}
+		return fmt.Sprintf("v%d.%d.%d", major, minor, patch)
+	}
+	if bump == "minor" {
+		return fmt.Sprintf("v%d.%d.0", major, minor+1)
+	}
+	return fmt.Sprintf("v%d.%d.%d", major, minor, patch+1)
+}
+
+func planRollout(g *moduleGraph, target, version, bump string) ([][]RolloutStep, error) {
+	if _, ok := g.repos[target]; !ok {
+		return nil, fmt.Errorf("%s is not one of the indexed modules", target)
+	}
+	affected := g.dependents(target)
+	stages, err := g.stages(affected)
+	if err != nil {
+		return nil, err
+	}
+
+	released := map[string]string{target: version}
+	var plan [][]RolloutStep
+	for _, stage := range stages {
+		var steps []RolloutStep
+		for _, m := range stage {
+			step := RolloutStep{Repo: g.repos[m].URL, Module: m}
+			if m == target {
+				step.Tag = version
+				steps = append(steps, step)
+				continue
+			}
+			for _, dep := range sortedKeys(g.deps[m]) {
+				if to, ok := released[dep]; ok {
+					step.Bumps = append(step.Bumps, RolloutBump{Path: dep, From: g.deps[m][dep], To: to})
+				}
+			}
+			if hasAffectedDependent(g, m, affected) {
+				step.Tag = nextVersion(g.currentRelease(m), bump)
+				released[m] = step.Tag
+			}
+			steps = append(steps, step)
+		}
+		plan = append(plan, steps)
+	}
+	return plan, nil
+}
+
+func hasAffectedDependent(g *moduleGraph, module string, affected map[string]bool) bool {
+	for m := range affected {
+		if _, ok := g.deps[m][module]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func printRollout(target, version string, plan [][]RolloutStep) {
+	fmt.Printf("Rollout plan for %s@%s:\n", target, version)
+	for i, stage := range plan {
+		fmt.Printf("Stage %d:\n", i+1)
+		for _, step := range stage {
+			fmt.Printf("- %s (%s)\n", step.Module, step.Repo)
+			for _, b := range step.Bumps {
+				fmt.Printf("    require %s %s (was %s)\n", b.Path, b.To, b.From)
+			}
+			if step.Tag != "" {
+				fmt.Printf("    tag %s\n", step.Tag)
+			}
+		}
+	}
+}
+
+func rolloutCommand(fs *flag.FlagSet) func() error {
+	indexPath := fs.String("index", defaultFleetIndex, "reverse-dependency index built by the index command")
+	reposFile := fs.String("repos", "", "analyze the repositories listed in this file instead of reading an index")
+	bump := fs.String("bump", "patch", "version component to increment when tagging downstream modules (patch or minor)")
+	return func() error { return runRollout(fs, *indexPath, *reposFile, *bump) }
+}
+
+func runRollout(fs *flag.FlagSet, indexPath, reposFile, bump string) error {
+	if fs.NArg() > 1 || (bump != "patch" && bump != "minor") {
+		return errUsage
+	}
+
+	var index *FleetIndex
+	var err error
+	if reposFile != "" {
+		var urls []string
+		if urls, err = readRepoList(reposFile); err != nil {
+			return fmt.Errorf("error reading repository list: %v", err)
+		}
+		index, err = buildFleetIndex(urls)
+	} else {
+		index, err = loadFleetIndex(indexPath)
+	}
+	if err != nil {
+		return fmt.Errorf("error loading index: %v", err)
+	}
+
+	g := newModuleGraph(index)
+	cycles := g.cycles()
+	if len(cycles) > 0 {
+		fmt.Println("Dependency cycles between internal modules:")
+		for _, c := range cycles {
+			fmt.Printf("- %s\n", strings.Join(c, " <-> "))
+		}
+	}
+
+	if fs.NArg() == 0 {
+		all := make(map[string]bool)
+		for _, m := range g.modules() {
+			all[m] = true
+		}
+		for _, c := range cycles {
+			for _, m := range c {
+				delete(all, m)
+			}
+		}
+		stages, err := g.stages(all)
+		if err != nil {
+			return fmt.Errorf("error ordering modules: %v", err)
+		}
+		fmt.Println("Update order:")
+		for i, stage := range stages {
+			fmt.Printf("Stage %d: %s\n", i+1, strings.Join(stage, ", "))
+		}
+		return nil
+	}
+
+	target, version, ok := strings.Cut(fs.Arg(0), "@")
+	if !ok || !semver.IsValid(version) {
+		return fmt.Errorf("expected <module>@<version>, got %q", fs.Arg(0))
+	}
+	plan, err := planRollout(g, target, version, bump)
+	if err != nil {
+		return fmt.Errorf("error planning rollout: %v", err)
+	}
+	printRollout(target, version, plan)
+	return nil
+}
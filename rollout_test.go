@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNextVersion(t *testing.T) {
+	tests := []struct {
+		current, bump, want string
+	}{
+		{"", "patch", "v0.1.0"},
+		{"not-a-version", "minor", "v0.1.0"},
+		{"v1.2.3", "patch", "v1.2.4"},
+		{"v1.2.3", "minor", "v1.3.0"},
+		{"v1", "patch", "v1.0.1"},
+		{"v1.3.0-rc.1", "patch", "v1.3.0"},
+		{"v1.3.0-rc.1", "minor", "v1.3.0"},
+		{"v1.2.4-beta", "minor", "v1.3.0"},
+		{"v1.2.4-0.20200101120000-abcdefabcdef", "patch", "v1.2.4"},
+		{"v1.2.4-0.20200101120000-abcdefabcdef", "minor", "v1.3.0"},
+		{"v1.3.0-rc.1.0.20200101120000-abcdefabcdef", "patch", "v1.3.0"},
+		{"v2.0.0-20200101120000-abcdefabcdef", "minor", "v2.0.0"},
+		{"v0.0.0-20200101120000-abcdefabcdef", "patch", "v0.0.1"},
+		{"v0.0.0-20200101120000-abcdefabcdef", "minor", "v0.1.0"},
+		{"v2.3.0+incompatible", "patch", "v2.3.1"},
+		{"v2.3.0+incompatible", "minor", "v2.4.0"},
+		{"v2.0.0-rc.1+incompatible", "patch", "v2.0.0"},
+	}
+	for _, tt := range tests {
+		if got := nextVersion(tt.current, tt.bump); got != tt.want {
+			t.Errorf("nextVersion(%q, %q) = %q, want %q", tt.current, tt.bump, got, tt.want)
+		}
+	}
+}
+
+func TestModuleGraphStages(t *testing.T) {
+	index := &FleetIndex{Repos: []FleetRepo{
+		{Module: "example.com/lib"},
+		{Module: "example.com/mid", Requires: []FleetRequirement{{Path: "example.com/lib", Version: "v1.0.0"}}},
+		{Module: "example.com/app", Requires: []FleetRequirement{
+			{Path: "example.com/mid", Version: "v1.0.0"},
+			{Path: "example.com/lib", Version: "v1.0.0"},
+			{Path: "github.com/external/x", Version: "v0.1.0"},
+		}},
+		{Module: "example.com/other"},
+	}}
+	g := newModuleGraph(index)
+	stages, err := g.stages(g.dependents("example.com/lib"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"example.com/lib"}, {"example.com/mid"}, {"example.com/app"}}
+	if !reflect.DeepEqual(stages, want) {
+		t.Errorf("stages = %v, want %v", stages, want)
+	}
+	if c := g.cycles(); len(c) != 0 {
+		t.Errorf("cycles = %v", c)
+	}
+
+	index.Repos[0].Requires = []FleetRequirement{{Path: "example.com/app", Version: "v1.0.0"}}
+	g = newModuleGraph(index)
+	if _, err := g.stages(g.dependents("example.com/lib")); err == nil {
+		t.Error("cyclic graph staged without error")
+	}
+	if c := g.cycles(); len(c) != 1 || len(c[0]) != 3 {
+		t.Errorf("cycles = %v, want one cycle of three modules", c)
+	}
+}
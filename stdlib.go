@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"go/version"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type stdlibReplacement struct {
+	Package     string
+	Replacement string
+	GoVersion   string
+	Note        string
+}
+
+// stdlibReplacements maps packages of third-party and x/ modules to the
+// standard library packages that supersede them, with the go version that
+// first ships the replacement.
+var stdlibReplacements = []stdlibReplacement{
+	{"golang.org/x/net/context", "context", "1.7", ""},
+	{"github.com/kardianos/osext", "os.Executable", "1.8", ""},
+	{"golang.org/x/sync/syncmap", "sync.Map", "1.9", ""},
+	{"github.com/mitchellh/go-homedir", "os.UserHomeDir", "1.12", "no ~ expansion"},
+	{"github.com/pkg/errors", "errors and fmt.Errorf with %w", "1.13", "no stack traces"},
+	{"golang.org/x/xerrors", "errors and fmt.Errorf with %w", "1.13", "no frame information"},
+	{"golang.org/x/crypto/ed25519", "crypto/ed25519", "1.13", ""},
+	{"go.uber.org/atomic", "sync/atomic typed values", "1.19", ""},
+	{"github.com/hashicorp/go-multierror", "errors.Join", "1.20", ""},
+	{"go.uber.org/multierr", "errors.Join", "1.20", ""},
+	{"golang.org/x/crypto/curve25519", "crypto/ecdh", "1.20", "X25519 only"},
+	{"golang.org/x/exp/slices", "slices", "1.21", "some signatures differ, e.g. SortFunc takes a cmp function"},
+	{"golang.org/x/exp/maps", "maps", "1.21", "Keys and Values return iterators since go 1.23"},
+	{"golang.org/x/exp/constraints", "cmp.Ordered", "1.21", "Integer, Float and friends have no standard counterpart"},
+	{"golang.org/x/exp/slog", "log/slog", "1.21", ""},
+	{"golang.org/x/exp/rand", "math/rand/v2", "1.22", ""},
+	{"github.com/gorilla/mux", "net/http.ServeMux patterns", "1.22", "method and wildcard patterns only"},
+	{"golang.org/x/crypto/sha3", "crypto/sha3", "1.24", ""},
+	{"golang.org/x/crypto/hkdf", "crypto/hkdf", "1.24", ""},
+	{"golang.org/x/crypto/pbkdf2", "crypto/pbkdf2", "1.24", ""},
+}
+
+type PackageReplacement struct {
+	Package     string `json:"Package"`
+	Replacement string `json:"Replacement"`
+	GoVersion   string `json:"GoVersion"`
+	Note        string `json:"Note,omitempty"`
+	ImportSites int    `json:"ImportSites"`
+	Available   bool   `json:"Available"`
+}
+
+type StdlibSuggestion struct {
+	Module      string               `json:"Module"`
+	Packages    []PackageReplacement `json:"Packages"`
+	ImportSites int                  `json:"ImportSites"`
+	// OtherPackages counts the packages of Module the main module imports
+	// that have no standard library replacement.
+	OtherPackages int `json:"OtherPackages,omitempty"`
+	// RequiredBy lists the other modules in the requirement graph that
+	// require Module; it stays in the build list as long as they do.
+	RequiredBy []string `json:"RequiredBy,omitempty"`
+	// Droppable is set when every package the main module imports from
+	// Module has a replacement available at the current go version and no
+	// other module requires it.
+	Droppable bool   `json:"Droppable"`
+	NeedsGo   string `json:"NeedsGo,omitempty"`
+}
+
+// suggestStdlibReplacements matches the packages imported by the main
+// module at modDir against the knowledge base and groups the matches by
+// the dependency providing them. The requirement graph tells which of
+// them other dependencies keep in the build list.
+func suggestStdlibReplacements(modDir, goVersion string, modules []ModuleInfo, graph []GraphEdge) ([]StdlibSuggestion, error) {
+	sites, err := importSites(modDir)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]stdlibReplacement, len(stdlibReplacements))
+	for _, r := range stdlibReplacements {
+		known[r.Package] = r
+	}
+
+	byModule := make(map[string]*StdlibSuggestion)
+	replaceable := make(map[string]int)
+	imported := make(map[string]int)
+	for p, n := range sites {
+		mod := moduleForPackage(p, modules)
+		if mod == "" {
+			continue
+		}
+		imported[mod]++
+		r, ok := known[p]
+		if !ok {
+			continue
+		}
+		s := byModule[mod]
+		if s == nil {
+			s = &StdlibSuggestion{Module: mod}
+			byModule[mod] = s
+		}
+		available := goVersion != "" && version.Compare("go"+goVersion, "go"+r.GoVersion) >= 0
+		s.Packages = append(s.Packages, PackageReplacement{
+			Package: p, Replacement: r.Replacement, GoVersion: r.GoVersion, Note: r.Note,
+			ImportSites: n, Available: available,
+		})
+		s.ImportSites += n
+		if available {
+			replaceable[mod]++
+		} else if s.NeedsGo == "" || version.Compare("go"+r.GoVersion, "go"+s.NeedsGo) > 0 {
+			s.NeedsGo = r.GoVersion
+		}
+	}
+
+	requiredBy := make(map[string]map[string]bool)
+	for _, e := range graph {
+		from, _, versioned := strings.Cut(e.From, "@")
+		to, _, _ := strings.Cut(e.To, "@")
+		// Edges from the main module carry no version.
+		if !versioned || byModule[to] == nil || from == to {
+			continue
+		}
+		if requiredBy[to] == nil {
+			requiredBy[to] = make(map[string]bool)
+		}
+		requiredBy[to][from] = true
+	}
+
+	var suggestions []StdlibSuggestion
+	for mod, s := range byModule {
+		s.OtherPackages = imported[mod] - len(s.Packages)
+		for from := range requiredBy[mod] {
+			s.RequiredBy = append(s.RequiredBy, from)
+		}
+		sort.Strings(s.RequiredBy)
+		s.Droppable = replaceable[mod] == imported[mod] && len(s.RequiredBy) == 0
+		sort.Slice(s.Packages, func(i, j int) bool { return s.Packages[i].Package < s.Packages[j].Package })
+		suggestions = append(suggestions, *s)
+	}
+	sort.Slice(suggestions, func(i, j int) bool { return suggestions[i].Module < suggestions[j].Module })
+	return suggestions, nil
+}
+
+// importSites counts, for every imported package, the files of the main
+// module that import it.
+func importSites(modDir string) (map[string]int, error) {
+	sites := make(map[string]int)
+	fset := token.NewFileSet()
+	err := walkModuleGoFiles(modDir, func(path string) error {
+		file, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
+		if err != nil {
+			return nil
+		}
+		for _, imp := range file.Imports {
+			if p, err := strconv.Unquote(imp.Path.Value); err == nil {
+				sites[p]++
+			}
+		}
+		return nil
+	})
+	return sites, err
+}
+
+// stdlibFindings reports the dependencies that can be dropped today.
+func stdlibFindings(suggestions []StdlibSuggestion) []Finding {
+	var findings []Finding
+	for _, s := range suggestions {
+		if !s.Droppable {
+			continue
+		}
+		var targets []string
+		for _, p := range s.Packages {
+			targets = append(targets, p.Replacement)
+		}
+		findings = append(findings, Finding{
+			Source:   "stdlib",
+			Module:   s.Module,
+			Severity: "info",
+			Message:  fmt.Sprintf("can be replaced by %s (%d import sites)", strings.Join(targets, ", "), s.ImportSites),
+		})
+	}
+	return findings
+}
+
+func printStdlibSuggestions(goVersion string, suggestions []StdlibSuggestion) {
+	if len(suggestions) == 0 {
+		return
+	}
+	fmt.Println("Standard library replacements:")
+	for _, s := range suggestions {
+		status := "can be dropped"
+		switch {
+		case s.NeedsGo != "":
+			status = fmt.Sprintf("needs go %s (go.mod has %s)", s.NeedsGo, goVersion)
+		case s.OtherPackages > 0:
+			status = "other packages of the module are still imported"
+		case len(s.RequiredBy) > 0:
+			status = fmt.Sprintf("no longer imported directly once replaced, but still required by %s", strings.Join(s.RequiredBy, ", "))
+		}
+		fmt.Printf("- %s: %s, %d import sites\n", s.Module, status, s.ImportSites)
+		for _, p := range s.Packages {
+			note := ""
+			if p.Note != "" {
+				note = " (" + p.Note + ")"
+			}
+			fmt.Printf("    %s -> %s since go %s, %d import sites%s\n", p.Package, p.Replacement, p.GoVersion, p.ImportSites, note)
+		}
+	}
+}
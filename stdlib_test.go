@@ -0,0 +1,162 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestImportSites(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":           "module example.com/m\n",
+		"a.go":             "package m\n\nimport (\n\t\"fmt\"\n\t\"github.com/pkg/errors\"\n)\n",
+		"b.go":             "package m\n\nimport \"github.com/pkg/errors\"\n",
+		"b_test.go":        "package m\n\nimport \"github.com/pkg/errors\"\n",
+		"sub/c.go":         "package sub\n\nimport (\n\t\"fmt\"\n\tx \"golang.org/x/exp/slices\"\n)\n",
+		"broken.go":        "package m\n\nimport \"unterminated\n",
+		"testdata/t.go":    "package t\n\nimport \"github.com/pkg/errors\"\n",
+		"nested/go.mod":    "module example.com/m/nested\n",
+		"nested/nested.go": "package nested\n\nimport \"github.com/pkg/errors\"\n",
+	})
+	sites, err := importSites(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"fmt": 2, "github.com/pkg/errors": 3, "golang.org/x/exp/slices": 1}
+	if !reflect.DeepEqual(sites, want) {
+		t.Errorf("importSites = %v, want %v", sites, want)
+	}
+}
+
+func TestSuggestStdlibReplacements(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/m\n",
+		"a.go": `package m
+
+import (
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/sha3"
+	"golang.org/x/exp/slices"
+	"github.com/pkg/errors"
+	"github.com/hashicorp/go-multierror"
+	"go.uber.org/multierr"
+)
+`,
+		"b.go": "package m\n\nimport (\n\t\"github.com/pkg/errors\"\n\t\"golang.org/x/exp/maps\"\n)\n",
+	})
+	modules := []ModuleInfo{
+		{Path: "example.com/m", Main: true},
+		{Path: "golang.org/x/crypto", Version: "v0.30.0"},
+		{Path: "golang.org/x/exp", Version: "v0.0.0-20240506185415-9bf2ced13842"},
+		{Path: "github.com/pkg/errors", Version: "v0.9.1"},
+		{Path: "github.com/hashicorp/go-multierror", Version: "v1.1.1"},
+		{Path: "go.uber.org/multierr", Version: "v1.11.0"},
+	}
+	graph := []GraphEdge{
+		{"example.com/m", "github.com/pkg/errors@v0.9.1"},
+		{"example.com/m", "go.uber.org/multierr@v1.11.0"},
+		{"example.com/m", "github.com/hashicorp/go-multierror@v1.1.1"},
+		{"go.uber.org/zap@v1.27.0", "go.uber.org/multierr@v1.10.0"},
+		{"go.uber.org/multierr@v1.11.0", "go.uber.org/multierr@v1.10.0"},
+	}
+
+	want := []StdlibSuggestion{
+		{
+			Module: "github.com/hashicorp/go-multierror",
+			Packages: []PackageReplacement{
+				{Package: "github.com/hashicorp/go-multierror", Replacement: "errors.Join", GoVersion: "1.20", ImportSites: 1, Available: true},
+			},
+			ImportSites: 1,
+			Droppable:   true,
+		},
+		{
+			Module: "github.com/pkg/errors",
+			Packages: []PackageReplacement{
+				{Package: "github.com/pkg/errors", Replacement: "errors and fmt.Errorf with %w", GoVersion: "1.13", Note: "no stack traces", ImportSites: 2, Available: true},
+			},
+			ImportSites: 2,
+			Droppable:   true,
+		},
+		{
+			Module: "go.uber.org/multierr",
+			Packages: []PackageReplacement{
+				{Package: "go.uber.org/multierr", Replacement: "errors.Join", GoVersion: "1.20", ImportSites: 1, Available: true},
+			},
+			ImportSites: 1,
+			RequiredBy:  []string{"go.uber.org/zap"},
+		},
+		{
+			Module: "golang.org/x/crypto",
+			Packages: []PackageReplacement{
+				{Package: "golang.org/x/crypto/sha3", Replacement: "crypto/sha3", GoVersion: "1.24", ImportSites: 1},
+			},
+			ImportSites:   1,
+			OtherPackages: 1,
+			NeedsGo:       "1.24",
+		},
+		{
+			Module: "golang.org/x/exp",
+			Packages: []PackageReplacement{
+				{Package: "golang.org/x/exp/maps", Replacement: "maps", GoVersion: "1.21", Note: "Keys and Values return iterators since go 1.23", ImportSites: 1, Available: true},
+				{Package: "golang.org/x/exp/slices", Replacement: "slices", GoVersion: "1.21", Note: "some signatures differ, e.g. SortFunc takes a cmp function", ImportSites: 1, Available: true},
+			},
+			ImportSites: 2,
+			Droppable:   true,
+		},
+	}
+	got, err := suggestStdlibReplacements(dir, "1.21", modules, graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("suggestions =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// The go directive decides which replacements are available.
+	gates := []struct {
+		goVersion, module string
+		available         bool
+		needsGo           string
+		droppable         bool
+	}{
+		{"1.20", "golang.org/x/exp", false, "1.21", false},
+		{"1.20", "github.com/hashicorp/go-multierror", true, "", true},
+		{"1.19", "github.com/hashicorp/go-multierror", false, "1.20", false},
+		{"", "github.com/pkg/errors", false, "1.13", false},
+		// sha3 is available, but bcrypt still needs x/crypto.
+		{"1.24", "golang.org/x/crypto", true, "", false},
+		{"1.24.1", "golang.org/x/exp", true, "", true},
+	}
+	for _, g := range gates {
+		got, err := suggestStdlibReplacements(dir, g.goVersion, modules, graph)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s StdlibSuggestion
+		for _, s = range got {
+			if s.Module == g.module {
+				break
+			}
+		}
+		if s.Module != g.module || s.Packages[0].Available != g.available || s.NeedsGo != g.needsGo || s.Droppable != g.droppable {
+			t.Errorf("go %q: %s = %+v, want available %v, needs go %q, droppable %v", g.goVersion, g.module, s, g.available, g.needsGo, g.droppable)
+		}
+	}
+}
+
+func TestStdlibFindings(t *testing.T) {
+	suggestions := []StdlibSuggestion{
+		{
+			Module:      "github.com/pkg/errors",
+			Packages:    []PackageReplacement{{Package: "github.com/pkg/errors", Replacement: "errors and fmt.Errorf with %w"}},
+			ImportSites: 3,
+			Droppable:   true,
+		},
+		{Module: "go.uber.org/multierr", RequiredBy: []string{"go.uber.org/zap"}},
+	}
+	want := []Finding{{Source: "stdlib", Module: "github.com/pkg/errors", Severity: "info", Message: "can be replaced by errors and fmt.Errorf with %w (3 import sites)"}}
+	if got := stdlibFindings(suggestions); !reflect.DeepEqual(got, want) {
+		t.Errorf("findings = %+v, want %+v", got, want)
+	}
+}
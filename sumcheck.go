@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+type SumConflict struct {
+	Path    string
+	Version string
+	Hashes  map[string][]string
+}
+
+func findSumConflicts(index *FleetIndex) []SumConflict {
+	type key struct{ path, version string }
+	hashes := make(map[key]map[string][]string)
+	for _, repo := range index.Repos {
+		name := repo.URL
+		for _, s := range repo.Sums {
+			k := key{s.Path, s.Version}
+			if hashes[k] == nil {
+				hashes[k] = make(map[string][]string)
+			}
+			if repos := hashes[k][s.Hash]; len(repos) == 0 || repos[len(repos)-1] != name {
+				hashes[k][s.Hash] = append(repos, name)
+			}
+		}
+	}
+
+	var conflicts []SumConflict
+	for k, byHash := range hashes {
+		if len(byHash) > 1 {
+			conflicts = append(conflicts, SumConflict{Path: k.path, Version: k.version, Hashes: byHash})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Path != conflicts[j].Path {
+			return conflicts[i].Path < conflicts[j].Path
+		}
+		return conflicts[i].Version < conflicts[j].Version
+	})
+	return conflicts
+}
+
+// reposWithoutSums returns the repositories that require modules but have no
+// go.sum hashes recorded, either because they lack a go.sum or because the
+// index was built before hashes were recorded.
+func reposWithoutSums(index *FleetIndex) []string {
+	var urls []string
+	for _, repo := range index.Repos {
+		if len(repo.Requires) > 0 && len(repo.Sums) == 0 {
+			urls = append(urls, repo.URL)
+		}
+	}
+	return urls
+}
+
+func printSumConflicts(conflicts []SumConflict, repos int) {
+	if len(conflicts) == 0 {
+		fmt.Printf("go.sum hashes are consistent across %d repositories.\n", repos)
+		return
+	}
+	fmt.Println("Conflicting go.sum hashes (possible tampering or proxy corruption):")
+	for _, c := range conflicts {
+		fmt.Printf("- %s %s\n", c.Path, c.Version)
+		hashes := make([]string, 0, len(c.Hashes))
+		for h := range c.Hashes {
+			hashes = append(hashes, h)
+		}
+		sort.Strings(hashes)
+		for _, h := range hashes {
+			fmt.Printf("    %s: %s\n", h, strings.Join(c.Hashes[h], ", "))
+		}
+	}
+}
+
+func sumCheckCommand(fs *flag.FlagSet) func() error {
+	indexPath := fs.String("index", defaultFleetIndex, "reverse-dependency index built by the index command")
+	reposFile := fs.String("repos", "", "file listing repository URLs, one per line")
+	return func() error { return runSumCheck(fs, *indexPath, *reposFile) }
+}
+
+func runSumCheck(fs *flag.FlagSet, indexPath, reposFile string) error {
+	urls, err := fleetRepoArgs(fs, reposFile)
+	if err != nil {
+		return err
+	}
+	var index *FleetIndex
+	if len(urls) > 0 {
+		index, err = buildFleetIndex(urls)
+	} else {
+		index, err = loadFleetIndex(indexPath)
+	}
+	if err != nil {
+		return fmt.Errorf("error loading index: %v", err)
+	}
+
+	unchecked := reposWithoutSums(index)
+	if len(unchecked) > 0 && len(unchecked) == len(index.Repos) {
+		return fmt.Errorf("no go.sum hashes recorded for any repository; re-run index to record them")
+	}
+	for _, url := range unchecked {
+		fmt.Fprintf(os.Stderr, "warning: no go.sum hashes recorded for %s; re-run index to check it\n", url)
+	}
+
+	conflicts := findSumConflicts(index)
+	printSumConflicts(conflicts, len(index.Repos)-len(unchecked))
+	if len(conflicts) > 0 {
+		return exitStatus(1)
+	}
+	return nil
+}
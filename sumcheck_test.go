@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindSumConflicts(t *testing.T) {
+	index := &FleetIndex{Repos: []FleetRepo{
+		{URL: "u/a", Sums: []FleetSum{
+			{Path: "example.com/x", Version: "v1.0.0", Hash: "h1:good="},
+			{Path: "example.com/y", Version: "v0.1.0", Hash: "h1:y="},
+		}},
+		{URL: "u/b", Sums: []FleetSum{{Path: "example.com/x", Version: "v1.0.0", Hash: "h1:bad="}}},
+		{URL: "u/c", Sums: []FleetSum{
+			{Path: "example.com/x", Version: "v1.0.0", Hash: "h1:good="},
+			{Path: "example.com/y", Version: "v0.1.0", Hash: "h1:y="},
+		}},
+	}}
+	want := []SumConflict{{
+		Path:    "example.com/x",
+		Version: "v1.0.0",
+		Hashes:  map[string][]string{"h1:good=": {"u/a", "u/c"}, "h1:bad=": {"u/b"}},
+	}}
+	if got := findSumConflicts(index); !reflect.DeepEqual(got, want) {
+		t.Errorf("conflicts = %+v, want %+v", got, want)
+	}
+}
+
+func TestReposWithoutSums(t *testing.T) {
+	requires := []FleetRequirement{{Path: "example.com/x", Version: "v1.0.0"}}
+	index := &FleetIndex{Repos: []FleetRepo{
+		{URL: "u/old", Requires: requires},
+		{URL: "u/new", Requires: requires, Sums: []FleetSum{{Path: "example.com/x", Version: "v1.0.0", Hash: "h1:x="}}},
+		{URL: "u/leaf"},
+	}}
+	if got, want := reposWithoutSums(index), []string{"u/old"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("reposWithoutSums = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"golang.org/x/mod/semver"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const defaultReportTemplate = `Module: {{.Module}}
+Go Module Version: {{.GoVersion}}
+{{if .Updates}}Dependencies that can be updated:
+{{range .Updates}}- {{.Path}}: {{.Version}} -> {{.Update.Version}}{{annotations .}}
+{{end}}{{else}}All dependencies are up to date.
+{{end}}{{if .Findings}}Findings:
+{{range .Findings}}- [{{.Severity}}] {{or .Module "(module)"}}: {{.Message}} ({{.Source}})
+{{end}}{{end}}`
+
+type ModuleGroup struct {
+	Key     string
+	Modules []ModuleInfo
+}
+
+var reportFuncs = template.FuncMap{
+	"annotations":   updateAnnotations,
+	"semverClass":   semverClass,
+	"libyear":       libyear,
+	"libyears":      totalLibyears,
+	"formatLibyear": formatLibyear,
+	"md":            markdownEscape,
+	"groupBy":       groupModules,
+	"join":          strings.Join,
+}
+
+func loadReportTemplate(path string) (*template.Template, error) {
+	if path == "" {
+		return template.New("report").Funcs(reportFuncs).Parse(defaultReportTemplate)
+	}
+	return template.New(filepath.Base(path)).Funcs(reportFuncs).ParseFiles(path)
+}
+
+func printResults(w io.Writer, tmpl *template.Template, report *Report) error {
+	return tmpl.Execute(w, report)
+}
+
+func semverClass(from, to string) string {
+	switch {
+	case !semver.IsValid(from) || !semver.IsValid(to) || semver.Compare(from, to) >= 0:
+		return "none"
+	case semver.Prerelease(to) != "":
+		return "prerelease"
+	case semver.Major(from) != semver.Major(to):
+		return "major"
+	case semver.MajorMinor(from) != semver.MajorMinor(to):
+		return "minor"
+	}
+	return "patch"
+}
+
+func updateClass(m ModuleInfo) string {
+	if m.Update == nil {
+		return "none"
+	}
+	return semverClass(m.Version, m.Update.Version)
+}
+
+// libyear is the time between the release of the version in use and the
+// release of its update, in years.
+func libyear(m ModuleInfo) float64 {
+	if m.Time == nil || m.Update == nil || m.Update.Time == nil || !m.Update.Time.After(*m.Time) {
+		return 0
+	}
+	return m.Update.Time.Sub(*m.Time).Hours() / (24 * 365.25)
+}
+
+// formatLibyear renders a libyear value with two decimals, e.g. "3.45".
+func formatLibyear(years float64) string {
+	return strconv.FormatFloat(years, 'f', 2, 64)
+}
+
+func totalLibyears(modules []ModuleInfo) float64 {
+	var total float64
+	for _, m := range modules {
+		total += libyear(m)
+	}
+	return total
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "|", `\|`, "#", `\#`,
+)
+
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+func groupModules(key string, modules []ModuleInfo) []ModuleGroup {
+	index := make(map[string]int)
+	var groups []ModuleGroup
+	for _, m := range modules {
+		var k string
+		switch key {
+		case "class":
+			k = updateClass(m)
+		case "direct":
+			k = "direct"
+			if m.Indirect {
+				k = "indirect"
+			}
+		case "pinned":
+			k = "unpinned"
+			if m.Pin.Active(time.Now()) {
+				k = "pinned"
+			}
+		case "host":
+			k, _, _ = strings.Cut(m.Path, "/")
+		default:
+			k = m.Path
+		}
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, ModuleGroup{Key: k})
+		}
+		groups[i].Modules = append(groups[i].Modules, m)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+	return groups
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// withUpdate returns m with an available update to version, released at t.
+func withUpdate(m ModuleInfo, version string, t *time.Time) ModuleInfo {
+	m.Update = &struct {
+		Path    string     `json:"Path"`
+		Version string     `json:"Version"`
+		Time    *time.Time `json:"Time,omitempty"`
+	}{Path: m.Path, Version: version, Time: t}
+	return m
+}
+
+func TestDefaultReportTemplate(t *testing.T) {
+	tmpl, err := loadReportTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	released := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := released.Add(time.Duration(1.5 * 365.25 * 24 * float64(time.Hour)))
+	report := &Report{
+		Module:    "example.com/m",
+		GoVersion: "1.22",
+		Updates: []ModuleInfo{
+			withUpdate(ModuleInfo{Path: "example.com/a", Version: "v1.0.0", Time: &released}, "v1.2.0", &updated),
+			withUpdate(ModuleInfo{Path: "example.com/b", Version: "v0.1.0", Deprecated: "use c"}, "v0.1.1", nil),
+		},
+		Findings: []Finding{{Source: "deny", Severity: "warning", Message: "denied"}},
+	}
+	var sb strings.Builder
+	if err := printResults(&sb, tmpl, report); err != nil {
+		t.Fatal(err)
+	}
+	// The default template must reproduce the report as printed before
+	// templates existed, byte for byte.
+	want := `Module: example.com/m
+Go Module Version: 1.22
+Dependencies that can be updated:
+- example.com/a: v1.0.0 -> v1.2.0
+- example.com/b: v0.1.0 -> v0.1.1 [deprecated: use c]
+Findings:
+- [warning] (module): denied (deny)
+`
+	if got := sb.String(); got != want {
+		t.Errorf("report =\n%s\nwant\n%s", got, want)
+	}
+
+	sb.Reset()
+	if err := printResults(&sb, tmpl, &Report{Module: "example.com/m", GoVersion: "1.22"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(sb.String(), "All dependencies are up to date.\n") {
+		t.Errorf("report without updates =\n%s", sb.String())
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	classes := []struct{ from, to, want string }{
+		{"v1.0.0", "v1.0.1", "patch"},
+		{"v1.0.0", "v1.1.0", "minor"},
+		{"v1.9.0", "v2.0.0", "major"},
+		{"v1.0.0", "v1.1.0-rc.1", "prerelease"},
+		{"v1.1.0", "v1.0.0", "none"},
+		{"bogus", "v1.0.0", "none"},
+	}
+	for _, c := range classes {
+		if got := semverClass(c.from, c.to); got != c.want {
+			t.Errorf("semverClass(%s, %s) = %s, want %s", c.from, c.to, got, c.want)
+		}
+	}
+	for in, want := range map[float64]string{0: "0.00", 3.4499: "3.45", 12: "12.00"} {
+		if got := formatLibyear(in); got != want {
+			t.Errorf("formatLibyear(%v) = %q, want %q", in, got, want)
+		}
+	}
+	if got := markdownEscape("a_b|*c*"); got != `a\_b\|\*c\*` {
+		t.Errorf("markdownEscape = %q", got)
+	}
+	groups := groupModules("host", []ModuleInfo{{Path: "golang.org/x/mod"}, {Path: "github.com/a/b"}, {Path: "golang.org/x/net"}})
+	if len(groups) != 2 || groups[0].Key != "github.com" || len(groups[1].Modules) != 2 {
+		t.Errorf("groupBy host = %+v", groups)
+	}
+}
@@ -0,0 +1,17 @@
+#!/bin/sh
+# Sample check plugin. It reads {"ProtocolVersion":1,"Report":{...}} on
+# stdin and writes {"Findings":[...]} on stdout, reporting every module of
+# the build list that is on the deny list below.
+input=$(cat)
+denied="github.com/pkg/errors golang.org/x/tools"
+sep=""
+printf '{"Findings":['
+for m in $denied; do
+	case "$input" in
+	*"\"Path\":\"$m\""*)
+		printf '%s{"Module":"%s","Severity":"warning","Message":"%s is on the deny list"}' "$sep" "$m" "$m"
+		sep=","
+		;;
+	esac
+done
+printf ']}\n'
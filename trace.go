@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const traceServiceName = "go-dep-analysis"
+
+type span struct {
+	traceID  [16]byte
+	id       [8]byte
+	parentID [8]byte
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    []string
+	err      error
+}
+
+type tracer struct {
+	mu      sync.Mutex
+	enabled bool
+	active  []*span
+	spans   []*span
+}
+
+var tracing tracer
+
+type traceConfig struct {
+	endpoint string
+	file     string
+}
+
+func addTraceFlags(fs *flag.FlagSet, cfg *traceConfig) {
+	fs.StringVar(&cfg.endpoint, "trace-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "export OpenTelemetry spans to this OTLP/HTTP endpoint")
+	fs.StringVar(&cfg.file, "trace-file", "", "write OpenTelemetry spans as OTLP JSON to this file")
+}
+
+func (cfg *traceConfig) start() {
+	tracing.mu.Lock()
+	defer tracing.mu.Unlock()
+	tracing.enabled = cfg.endpoint != "" || cfg.file != ""
+}
+
+// startSpan starts a span as a child of the innermost span still running.
+// attrs are alternating keys and values. It returns nil when tracing is
+// disabled; ending a nil span is a no-op.
+func startSpan(name string, attrs ...string) *span {
+	tracing.mu.Lock()
+	defer tracing.mu.Unlock()
+	if !tracing.enabled {
+		return nil
+	}
+	s := &span{name: name, start: time.Now(), attrs: attrs}
+	rand.Read(s.id[:])
+	if n := len(tracing.active); n > 0 {
+		parent := tracing.active[n-1]
+		s.traceID, s.parentID = parent.traceID, parent.id
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	tracing.active = append(tracing.active, s)
+	return s
+}
+
+func (s *span) SetAttributes(attrs ...string) {
+	if s == nil {
+		return
+	}
+	tracing.mu.Lock()
+	defer tracing.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+func (s *span) End(err error) {
+	if s == nil {
+		return
+	}
+	tracing.mu.Lock()
+	defer tracing.mu.Unlock()
+	s.end, s.err = time.Now(), err
+	for i := len(tracing.active) - 1; i >= 0; i-- {
+		if tracing.active[i] == s {
+			tracing.active = append(tracing.active[:i], tracing.active[i+1:]...)
+			break
+		}
+	}
+	tracing.spans = append(tracing.spans, s)
+}
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func otlpAttributes(kv []string) []otlpAttribute {
+	var attrs []otlpAttribute
+	for i := 0; i+1 < len(kv); i += 2 {
+		a := otlpAttribute{Key: kv[i]}
+		a.Value.StringValue = kv[i+1]
+		attrs = append(attrs, a)
+	}
+	return attrs
+}
+
+func (s *span) otlp() otlpSpan {
+	o := otlpSpan{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.id[:]),
+		Name:              s.name,
+		Kind:              1,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Attributes:        otlpAttributes(s.attrs),
+	}
+	if s.parentID != [8]byte{} {
+		o.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	if s.err != nil {
+		o.Status = otlpStatus{Code: 2, Message: s.err.Error()}
+	}
+	return o
+}
+
+func tracePayload(spans []*span) ([]byte, error) {
+	var scope otlpScopeSpans
+	scope.Scope.Name = traceServiceName
+	for _, s := range spans {
+		scope.Spans = append(scope.Spans, s.otlp())
+	}
+	var resource otlpResourceSpans
+	resource.Resource.Attributes = otlpAttributes([]string{"service.name", traceServiceName})
+	resource.ScopeSpans = []otlpScopeSpans{scope}
+	return json.MarshalIndent(otlpTraces{ResourceSpans: []otlpResourceSpans{resource}}, "", "  ")
+}
+
+// finishTracing ends the spans still running and exports everything
+// recorded so far.
+func finishTracing() {
+	tracing.mu.Lock()
+	active := append([]*span(nil), tracing.active...)
+	tracing.mu.Unlock()
+	for i := len(active) - 1; i >= 0; i-- {
+		active[i].End(nil)
+	}
+	if err := globals.trace.flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: exporting traces: %v\n", err)
+	}
+}
+
+// flush exports the finished spans. A generic OTLP endpoint gets the
+// /v1/traces path appended, as the OpenTelemetry exporters do.
+func (cfg *traceConfig) flush() error {
+	tracing.mu.Lock()
+	spans := tracing.spans
+	tracing.spans = nil
+	tracing.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+	data, err := tracePayload(spans)
+	if err != nil {
+		return err
+	}
+	if cfg.file != "" {
+		if err := os.WriteFile(cfg.file, append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	if cfg.endpoint == "" {
+		return nil
+	}
+	url := strings.TrimSuffix(cfg.endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("POST %s: %s", url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestTraceExport(t *testing.T) {
+	payloads := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s %s with content type %q", r.Method, r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		payloads <- data
+	}))
+	defer srv.Close()
+
+	saved := globals.trace
+	globals.trace = traceConfig{endpoint: srv.URL}
+	defer func() {
+		globals.trace = saved
+		tracing = tracer{}
+	}()
+	globals.trace.start()
+
+	root := startSpan("analyze", "command.args", "https://example.com/repo.git")
+	clone := startSpan("clone", "repo.url", "https://example.com/repo.git")
+	clone.End(nil)
+	list := startSpan("go list")
+	lookup := startSpan("proxy lookup", "module.path", "example.com/a")
+	lookup.SetAttributes("module.version", "v1.2.0")
+	lookup.End(errors.New("404 Not Found"))
+	startSpan("left running")
+	list.End(nil)
+	root.End(nil)
+	finishTracing()
+
+	var traces otlpTraces
+	if err := json.Unmarshal(<-payloads, &traces); err != nil {
+		t.Fatal(err)
+	}
+	if len(traces.ResourceSpans) != 1 || len(traces.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected payload shape: %+v", traces)
+	}
+	if attrs := traces.ResourceSpans[0].Resource.Attributes; len(attrs) != 1 || attrs[0].Value.StringValue != traceServiceName {
+		t.Errorf("resource attributes = %+v", attrs)
+	}
+	spans := make(map[string]otlpSpan)
+	for _, s := range traces.ResourceSpans[0].ScopeSpans[0].Spans {
+		spans[s.Name] = s
+	}
+	if len(spans) != 5 {
+		t.Fatalf("exported spans %v, want 5", spans)
+	}
+
+	parents := map[string]string{
+		"analyze":      "",
+		"clone":        "analyze",
+		"go list":      "analyze",
+		"proxy lookup": "go list",
+		"left running": "go list",
+	}
+	traceID := spans["analyze"].TraceID
+	for name, parent := range parents {
+		s := spans[name]
+		want := ""
+		if parent != "" {
+			want = spans[parent].SpanID
+		}
+		if s.ParentSpanID != want {
+			t.Errorf("%s: parent span %q, want %q (%s)", name, s.ParentSpanID, want, parent)
+		}
+		if s.TraceID != traceID {
+			t.Errorf("%s: trace %s, want %s", name, s.TraceID, traceID)
+		}
+		start, _ := strconv.ParseInt(s.StartTimeUnixNano, 10, 64)
+		end, _ := strconv.ParseInt(s.EndTimeUnixNano, 10, 64)
+		if start == 0 || end < start {
+			t.Errorf("%s: runs from %s to %s", name, s.StartTimeUnixNano, s.EndTimeUnixNano)
+		}
+	}
+
+	lookupSpan := spans["proxy lookup"]
+	if lookupSpan.Status.Code != 2 || lookupSpan.Status.Message != "404 Not Found" {
+		t.Errorf("failed span status = %+v", lookupSpan.Status)
+	}
+	if got := otlpAttributesMap(lookupSpan.Attributes); got["module.path"] != "example.com/a" || got["module.version"] != "v1.2.0" {
+		t.Errorf("proxy lookup attributes = %v", got)
+	}
+	if spans["clone"].Status.Code != 0 {
+		t.Errorf("successful span status = %+v", spans["clone"].Status)
+	}
+}
+
+func TestTracingDisabled(t *testing.T) {
+	tracing = tracer{}
+	if s := startSpan("x"); s != nil {
+		t.Fatalf("startSpan returned a span with tracing disabled")
+	}
+	var s *span
+	s.SetAttributes("k", "v")
+	s.End(errors.New("ignored"))
+}
+
+func otlpAttributesMap(attrs []otlpAttribute) map[string]string {
+	m := make(map[string]string)
+	for _, a := range attrs {
+		m[a.Key] = a.Value.StringValue
+	}
+	return m
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+func versionsCommand(fs *flag.FlagSet) func() error {
+	retracted := fs.Bool("retracted", false, "include retracted versions")
+	return func() error { return runVersions(fs, *retracted) }
+}
+
+func runVersions(fs *flag.FlagSet, retracted bool) error {
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+	args := []string{"list", "-m", "-versions", "-json"}
+	if retracted {
+		args = append(args, "-retracted")
+	}
+	sp := startSpan("proxy lookup", "module.path", fs.Arg(0), "module.query", "versions")
+	out, err := runGo(os.TempDir(), append(args, fs.Arg(0)+"@latest")...)
+	sp.End(err)
+	if err != nil {
+		return fmt.Errorf("error listing versions: %v", err)
+	}
+	var m ModuleInfo
+	if err := json.Unmarshal(out, &m); err != nil {
+		return fmt.Errorf("error parsing go list output: %v", err)
+	}
+	if len(m.Versions) == 0 {
+		fmt.Printf("%s has no tagged versions (latest %s).\n", m.Path, m.Version)
+		return nil
+	}
+	for _, v := range slices.Backward(m.Versions) {
+		if v == m.Version {
+			fmt.Printf("%s (latest)\n", v)
+		} else {
+			fmt.Println(v)
+		}
+	}
+	return nil
+}
+
+func upgradeCommand(fs *flag.FlagSet) func() error {
+	dir := fs.String("C", ".", "directory of the module to upgrade")
+	indirect := fs.Bool("indirect", false, "also upgrade indirect dependencies")
+	dryRun := fs.Bool("n", false, "print the upgrades without applying them")
+	return func() error { return runUpgrade(fs, *dir, *indirect, *dryRun) }
+}
+
+func runUpgrade(fs *flag.FlagSet, dir string, indirect, dryRun bool) error {
+	goModPath := filepath.Join(dir, "go.mod")
+	pins, err := readPins(goModPath)
+	if err != nil {
+		return fmt.Errorf("error reading pins: %v", err)
+	}
+	deps, err := getDependencies(dir)
+	if err != nil {
+		return fmt.Errorf("error getting dependencies: %v", err)
+	}
+	applyPins(deps, pins)
+
+	selected := make(map[string]bool)
+	for _, arg := range fs.Args() {
+		selected[arg] = true
+	}
+	var upgraded int
+	for _, dep := range deps {
+		if len(selected) > 0 {
+			if !selected[dep.Path] {
+				continue
+			}
+			delete(selected, dep.Path)
+		} else if dep.Indirect && !indirect {
+			continue
+		}
+		if dep.Pin.Active(time.Now()) {
+			fmt.Printf("skipping %s %s: %s (go.mod:%d)\n", dep.Path, dep.Version, dep.Pin, dep.Pin.Line)
+			continue
+		}
+		fmt.Printf("upgrading %s %s -> %s\n", dep.Path, dep.Version, dep.Update.Version)
+		if dryRun {
+			continue
+		}
+		if err := applyUpdate(dir, dep); err != nil {
+			return fmt.Errorf("error upgrading %s: %v", dep.Path, err)
+		}
+		upgraded++
+	}
+	var missing []string
+	for path := range selected {
+		missing = append(missing, path)
+	}
+	sort.Strings(missing)
+	for _, path := range missing {
+		fmt.Printf("skipping %s: not required or already up to date\n", path)
+	}
+	if upgraded > 0 {
+		if _, err := runGo(dir, "mod", "tidy"); err != nil {
+			return fmt.Errorf("error tidying go.mod: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRunUpgrade(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	proxy := writeFileProxy(t, map[string][]string{
+		"example.com/a": {"v1.0.0", "v1.1.0"},
+		"example.com/b": {"v1.0.0", "v1.2.0"},
+		"example.com/c": {"v1.0.0", "v1.0.1"},
+	}, nil)
+	t.Setenv("GOPROXY", "file://"+filepath.ToSlash(proxy))
+	t.Setenv("GOSUMDB", "off")
+	t.Setenv("GOPRIVATE", "")
+	t.Setenv("GOFLAGS", "-mod=mod -modcacherw")
+	t.Setenv("GOMODCACHE", t.TempDir())
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": `module example.com/m
+
+go 1.21
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.0.0 // depanalysis:pin reason="breaks the API"
+	example.com/c v1.0.0 // indirect
+)
+`,
+		"m.go": "package m\n\nimport (\n\t_ \"example.com/a\"\n\t_ \"example.com/b\"\n\t_ \"example.com/c\"\n)\n",
+	})
+	upgrade := func(args ...string) {
+		t.Helper()
+		fs, run := newCommandFlags(lookupCommand("upgrade"))
+		if err := fs.Parse(append([]string{"-C", dir}, args...)); err != nil {
+			t.Fatal(err)
+		}
+		if err := run(); err != nil {
+			t.Fatalf("upgrade %q: %v", args, err)
+		}
+	}
+	check := func(step string, want map[string]string) {
+		t.Helper()
+		f, err := readRequirements(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]string)
+		for _, r := range f.Require {
+			got[r.Mod.Path] = r.Mod.Version
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: requirements %v, want %v", step, got, want)
+		}
+	}
+
+	upgrade("-n")
+	check("dry run", map[string]string{"example.com/a": "v1.0.0", "example.com/b": "v1.0.0", "example.com/c": "v1.0.0"})
+
+	// Naming a module upgrades it even if indirect; unknown names are
+	// skipped.
+	upgrade("example.com/c", "example.com/unknown")
+	check("named", map[string]string{"example.com/a": "v1.0.0", "example.com/b": "v1.0.0", "example.com/c": "v1.0.1"})
+
+	upgrade()
+	check("all", map[string]string{"example.com/a": "v1.1.0", "example.com/b": "v1.0.0", "example.com/c": "v1.0.1"})
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"golang.org/x/mod/semver"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultVulnDB = "https://vuln.go.dev"
+
+type osvEntry struct {
+	ID       string   `json:"id"`
+	Summary  string   `json:"summary"`
+	Aliases  []string `json:"aliases"`
+	Affected []struct {
+		Module struct {
+			Path string `json:"path"`
+		} `json:"module"`
+		Ranges []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced string `json:"introduced"`
+				Fixed      string `json:"fixed"`
+			} `json:"events"`
+		} `json:"ranges"`
+	} `json:"affected"`
+}
+
+type Vulnerability struct {
+	ID      string `json:"ID"`
+	Summary string `json:"Summary,omitempty"`
+	Fixed   string `json:"Fixed,omitempty"`
+}
+
+type vulnDB struct {
+	base    string
+	client  *http.Client
+	mu      sync.Mutex
+	modules map[string][]string
+	entries map[string]*osvEntry
+	err     error
+}
+
+func newVulnDB() *vulnDB {
+	base := os.Getenv("GOVULNDB")
+	if base == "" {
+		base = defaultVulnDB
+	}
+	return &vulnDB{
+		base:    strings.TrimSuffix(base, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+		entries: make(map[string]*osvEntry),
+	}
+}
+
+func (db *vulnDB) fetch(path string, v any) error {
+	var data []byte
+	if strings.HasPrefix(db.base, "file://") {
+		u, err := url.Parse(db.base)
+		if err != nil {
+			return err
+		}
+		if data, err = os.ReadFile(u.Path + path); err != nil {
+			return err
+		}
+	} else {
+		resp, err := db.client.Get(db.base + path)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("GET %s%s: %s", db.base, path, resp.Status)
+		}
+		if data, err = io.ReadAll(resp.Body); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (db *vulnDB) loadIndex() error {
+	if db.modules != nil || db.err != nil {
+		return db.err
+	}
+	var index []struct {
+		Path  string `json:"path"`
+		Vulns []struct {
+			ID string `json:"id"`
+		} `json:"vulns"`
+	}
+	if err := db.fetch("/index/modules.json", &index); err != nil {
+		db.err = err
+		return err
+	}
+	db.modules = make(map[string][]string, len(index))
+	for _, m := range index {
+		for _, v := range m.Vulns {
+			db.modules[m.Path] = append(db.modules[m.Path], v.ID)
+		}
+	}
+	return nil
+}
+
+func (db *vulnDB) Vulnerabilities(modPath, version string) ([]Vulnerability, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if err := db.loadIndex(); err != nil {
+		return nil, err
+	}
+	var vulns []Vulnerability
+	for _, id := range db.modules[modPath] {
+		entry, ok := db.entries[id]
+		if !ok {
+			entry = &osvEntry{}
+			if err := db.fetch("/ID/"+id+".json", entry); err != nil {
+				return nil, err
+			}
+			db.entries[id] = entry
+		}
+		if fixed, affected := entry.affects(modPath, version); affected {
+			vulns = append(vulns, Vulnerability{ID: entry.ID, Summary: entry.Summary, Fixed: fixed})
+		}
+	}
+	return vulns, nil
+}
+
+func (e *osvEntry) affects(modPath, version string) (fixed string, affected bool) {
+	for _, a := range e.Affected {
+		if a.Module.Path != modPath {
+			continue
+		}
+		for _, r := range a.Ranges {
+			if r.Type != "SEMVER" {
+				continue
+			}
+			var introduced string
+			for _, ev := range r.Events {
+				if ev.Introduced != "" {
+					introduced = ev.Introduced
+					continue
+				}
+				if ev.Fixed != "" && introduced != "" {
+					if versionInRange(version, introduced, ev.Fixed) {
+						return "v" + ev.Fixed, true
+					}
+					introduced = ""
+				}
+			}
+			if introduced != "" && versionInRange(version, introduced, "") {
+				return "", true
+			}
+		}
+	}
+	return "", false
+}
+
+func versionInRange(version, introduced, fixed string) bool {
+	if introduced != "0" && semver.Compare(version, "v"+introduced) < 0 {
+		return false
+	}
+	return fixed == "" || semver.Compare(version, "v"+fixed) < 0
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestVersionInRange(t *testing.T) {
+	tests := []struct {
+		version, introduced, fixed string
+		want                       bool
+	}{
+		{"v1.0.0", "0", "", true},
+		{"v0.0.1", "0", "1.2.0", true},
+		{"v1.2.0", "0", "1.2.0", false},
+		{"v1.1.9", "1.1.0", "1.2.0", true},
+		{"v1.1.0", "1.1.0", "1.2.0", true},
+		{"v1.0.9", "1.1.0", "1.2.0", false},
+		{"v1.2.1", "1.1.0", "1.2.0", false},
+		{"v2.0.0", "1.1.0", "", true},
+		{"v1.2.0-rc.1", "1.1.0", "1.2.0", true},
+		{"v0.0.0-20240101000000-abcdef123456", "0", "0.1.0", true},
+	}
+	for _, tt := range tests {
+		if got := versionInRange(tt.version, tt.introduced, tt.fixed); got != tt.want {
+			t.Errorf("versionInRange(%s, %q, %q) = %v, want %v", tt.version, tt.introduced, tt.fixed, got, tt.want)
+		}
+	}
+}
+
+const testOSVEntry = `{
+	"id": "GO-2024-0001",
+	"summary": "Panic on crafted input",
+	"affected": [
+		{
+			"module": {"path": "example.com/other"},
+			"ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}]}]
+		},
+		{
+			"module": {"path": "example.com/a"},
+			"ranges": [
+				{"type": "ECOSYSTEM", "events": [{"introduced": "0"}]},
+				{"type": "SEMVER", "events": [
+					{"introduced": "0"},
+					{"fixed": "1.2.3"},
+					{"introduced": "1.5.0"},
+					{"fixed": "1.5.2"},
+					{"introduced": "2.0.0"}
+				]}
+			]
+		}
+	]
+}`
+
+func TestOSVAffects(t *testing.T) {
+	var entry osvEntry
+	if err := json.Unmarshal([]byte(testOSVEntry), &entry); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path, version string
+		fixed         string
+		affected      bool
+	}{
+		{"example.com/a", "v1.0.0", "v1.2.3", true},
+		{"example.com/a", "v1.2.3", "", false},
+		{"example.com/a", "v1.4.0", "", false},
+		{"example.com/a", "v1.5.0", "v1.5.2", true},
+		{"example.com/a", "v1.5.2", "", false},
+		{"example.com/a", "v2.1.0", "", true},
+		{"example.com/other", "v9.0.0", "", true},
+		{"example.com/unrelated", "v1.0.0", "", false},
+	}
+	for _, tt := range tests {
+		fixed, affected := entry.affects(tt.path, tt.version)
+		if fixed != tt.fixed || affected != tt.affected {
+			t.Errorf("affects(%s@%s) = %q, %v; want %q, %v", tt.path, tt.version, fixed, affected, tt.fixed, tt.affected)
+		}
+	}
+}
+
+func TestVulnerabilitiesFileDB(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"index/modules.json":   `[{"path": "example.com/a", "vulns": [{"id": "GO-2024-0001"}]}]`,
+		"ID/GO-2024-0001.json": testOSVEntry,
+	})
+	t.Setenv("GOVULNDB", "file://"+filepath.ToSlash(dir))
+	db := newVulnDB()
+
+	vulns, err := db.Vulnerabilities("example.com/a", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Vulnerability{{ID: "GO-2024-0001", Summary: "Panic on crafted input", Fixed: "v1.2.3"}}
+	if !reflect.DeepEqual(vulns, want) {
+		t.Errorf("vulnerabilities = %+v, want %+v", vulns, want)
+	}
+	if vulns, err := db.Vulnerabilities("example.com/a", "v1.3.0"); err != nil || len(vulns) != 0 {
+		t.Errorf("fixed version: %+v, %v; want none", vulns, err)
+	}
+	if vulns, err := db.Vulnerabilities("example.com/clean", "v1.0.0"); err != nil || len(vulns) != 0 {
+		t.Errorf("module without entries: %+v, %v; want none", vulns, err)
+	}
+}